@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"pos/config"
+	"pos/models"
+)
+
+const FakeProviderName = "fake"
+
+// FakeProvider is an in-memory provider for local development, available only
+// with PAYMENT_PROVIDER=fake. Intents are
+// authorized on creation and succeed on capture. When PAYMENT_FAKE_WEBHOOK_URL
+// is set, captures and refunds are followed by a webhook signed with
+// PAYMENT_WEBHOOK_SECRET, like a real gateway would send.
+type FakeProvider struct {
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	Intent
+	refunded models.Money
+}
+
+var fakeOnce sync.Once
+
+// enableFakeProvider registers the fake provider, but only when it is selected
+// explicitly with PAYMENT_PROVIDER=fake, so a deploy without payment
+// configuration never accepts fake captures.
+func enableFakeProvider() {
+	if config.LoadConfig("PAYMENT_PROVIDER") != FakeProviderName {
+		return
+	}
+	fakeOnce.Do(func() {
+		Register(NewFakeProvider())
+	})
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{intents: map[string]*fakeIntent{}}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) CreateIntent(_ context.Context, req IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidIntent)
+	}
+
+	id := "fake_pi_" + randomHex()
+	intent := &fakeIntent{Intent: Intent{
+		ID:           id,
+		Status:       models.PaymentIntentRequiresCapture,
+		Amount:       req.Amount,
+		ClientSecret: id + "_secret_" + randomHex(),
+	}}
+
+	p.mu.Lock()
+	p.intents[id] = intent
+	p.mu.Unlock()
+
+	return &intent.Intent, nil
+}
+
+func (p *FakeProvider) Capture(_ context.Context, intentID string) (*Intent, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != models.PaymentIntentRequiresCapture && intent.Status != models.PaymentIntentSucceeded {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: intent is %s", ErrInvalidIntent, intent.Status)
+	}
+	captured := intent.Status == models.PaymentIntentRequiresCapture
+	intent.Status = models.PaymentIntentSucceeded
+	result := intent.Intent
+	result.ClientSecret = ""
+	p.mu.Unlock()
+
+	if captured {
+		p.sendWebhook(WebhookEvent{Type: EventPaymentSucceeded, IntentID: intentID, Amount: result.Amount})
+	}
+	return &result, nil
+}
+
+func (p *FakeProvider) Refund(_ context.Context, intentID string, amount models.Money) (*Refund, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != models.PaymentIntentSucceeded {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: intent is %s", ErrInvalidIntent, intent.Status)
+	}
+	if amount <= 0 || intent.refunded+amount > intent.Amount {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: refund of %s exceeds the captured amount", ErrInvalidIntent, amount)
+	}
+	intent.refunded += amount
+	if intent.refunded == intent.Amount {
+		intent.Status = models.PaymentIntentRefunded
+	}
+	refunded := intent.refunded
+	p.mu.Unlock()
+
+	refund := &Refund{ID: "fake_re_" + randomHex(), IntentID: intentID, Amount: amount}
+	p.sendWebhook(WebhookEvent{Type: EventRefundSucceeded, IntentID: intentID, Amount: amount, AmountRefunded: refunded})
+	return refund, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := VerifySignature(config.LoadConfig("PAYMENT_WEBHOOK_SECRET"), payload, header.Get(SignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, fmt.Errorf("invalid webhook payload: missing event id or type")
+	}
+	return &event, nil
+}
+
+// sendWebhook posts a signed event to PAYMENT_FAKE_WEBHOOK_URL in the background.
+func (p *FakeProvider) sendWebhook(event WebhookEvent) {
+	url := config.LoadConfig("PAYMENT_FAKE_WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+	event.ID = "fake_evt_" + randomHex()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("fake payment provider: could not encode webhook: %v", err)
+		return
+	}
+
+	go func() {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			log.Printf("fake payment provider: could not build webhook request: %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, SignPayload(config.LoadConfig("PAYMENT_WEBHOOK_SECRET"), payload, time.Now()))
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Printf("fake payment provider: webhook delivery failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+func randomHex() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
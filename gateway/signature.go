@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature in the form "t=<unix time>,v1=<hex HMAC-SHA256>".
+const SignatureHeader = "Payment-Signature"
+
+// signatureTolerance is how old a signed webhook may be before it is rejected as a replay.
+const signatureTolerance = 5 * time.Minute
+
+// SignPayload returns the signature header value for a webhook payload sent at the given time.
+func SignPayload(secret string, payload []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, computeSignature(secret, timestamp, payload))
+}
+
+// VerifySignature checks a signature header produced by SignPayload.
+func VerifySignature(secret string, payload []byte, header string) error {
+	if secret == "" {
+		return fmt.Errorf("%w: no webhook secret configured", ErrInvalidSignature)
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := computeSignature(secret, timestamp, payload)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func computeSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	now := time.Now()
+	valid := SignPayload(secret, payload, now)
+	_, signature, _ := strings.Cut(valid, ",v1=")
+
+	tests := []struct {
+		name    string
+		secret  string
+		payload []byte
+		header  string
+		wantErr bool
+	}{
+		{"valid", secret, payload, valid, false},
+		{"spaces and extra fields", secret, payload, fmt.Sprintf(" t=%d , v0=abc, v1=%s", now.Unix(), signature), false},
+		{"slightly in the future", secret, payload, SignPayload(secret, payload, now.Add(time.Minute)), false},
+		{"no secret configured", "", payload, valid, true},
+		{"wrong secret", "other", payload, valid, true},
+		{"tampered payload", secret, []byte(`{"id":"evt_2"}`), valid, true},
+		{"tampered signature", secret, payload, fmt.Sprintf("t=%d,v1=%x", now.Unix(), 0xdead), true},
+		{"timestamp changed", secret, payload, fmt.Sprintf("t=%d,v1=%s", now.Unix()+1, signature), true},
+		{"too old", secret, payload, SignPayload(secret, payload, now.Add(-signatureTolerance-time.Minute)), true},
+		{"too far in the future", secret, payload, SignPayload(secret, payload, now.Add(signatureTolerance+time.Minute)), true},
+		{"missing timestamp", secret, payload, "v1=" + signature, true},
+		{"missing signature", secret, payload, fmt.Sprintf("t=%d", now.Unix()), true},
+		{"empty header", secret, payload, "", true},
+	}
+	for _, tt := range tests {
+		err := VerifySignature(tt.secret, tt.payload, tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifySignature() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: VerifySignature() error = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+}
@@ -1,11 +1,9 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
-	"pos/config"
 	"pos/database"
 	"pos/models"
 	"strconv"
@@ -16,13 +14,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm/clause"
 )
 
-type AuthClaims struct {
-	jwt.RegisteredClaims
-	Issuer string `json:"issuer"`
-}
-
 type CreateUserInput struct {
 	Username string  `json:"username" binding:"required"`
 	Name     string  `json:"name" binding:"required"`
@@ -30,8 +24,9 @@ type CreateUserInput struct {
 }
 
 type LoginInput struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name"` // Shown in the session list, e.g. "Front counter tablet"
 }
 
 type LoginResponse struct {
@@ -43,12 +38,31 @@ type RefreshTokenInput struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-func generateRefreshToken() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"` // Also ends the device's session when given
+}
+
+// issueAccessToken signs a short-lived access token for the user. Each token
+// gets its own ID so that it can be revoked on logout.
+func issueAccessToken(user models.User) (string, error) {
+	jti, err := generateRefreshToken()
+	if err != nil {
 		return "", err
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
+
+	now := time.Now()
+	claims := utils.AccessTokenClaims{
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(user.ID)),
+			Issuer:    utils.JWTIssuer(),
+			Audience:  jwt.ClaimStrings{utils.JWTAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(utils.AccessTokenTTL())),
+			ID:        jti,
+		},
+	}
+	return utils.SignAccessToken(claims)
 }
 
 // @Summary Register a new user
@@ -101,7 +115,7 @@ func Register(c *gin.Context) {
 }
 
 // @Summary Login a user
-// @Description Login a user with username and password to get access and refresh tokens.
+// @Description Login a user with username and password to get access and refresh tokens. Each login starts a new session for the device.
 // @Tags Auth
 // @Accept  json
 // @Produce  json
@@ -139,17 +153,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	claims := AuthClaims{
-		Issuer: strconv.Itoa(int(user.ID)),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(config.LoadConfig("JWT_SECRET")))
-
+	tokenString, err := issueAccessToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{
 			Message: "Could not login",
@@ -157,7 +161,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	refreshToken, err := generateRefreshToken()
+	familyID, err := generateRefreshToken()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{
 			Message: "Could not generate refresh token",
@@ -165,8 +169,20 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	user.RefreshToken = refreshToken
-	database.DB.Save(&user)
+	session := models.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		DeviceName: data.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+	refreshToken, err := startSession(database.DB, &session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{
+			Message: "Could not generate refresh token",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, LoginResponse{
 		Token:        tokenString,
@@ -175,7 +191,7 @@ func Login(c *gin.Context) {
 }
 
 // @Summary Refresh access token
-// @Description Refresh an expired access token using a refresh token.
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; using one again revokes every session rotated from the same login.
 // @Tags Auth
 // @Accept  json
 // @Produce  json
@@ -183,19 +199,36 @@ func Login(c *gin.Context) {
 // @Success 200 {object} LoginResponse
 // @Failure 400 {object} models.MessageResponse
 // @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
 // @Router /auth/refresh [post]
 func RefreshToken(c *gin.Context) {
-	var data struct {
-		RefreshToken string `json:"refresh_token"`
-	}
+	var data RefreshTokenInput
 
 	if err := c.BindJSON(&data); err != nil {
 		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid request body"})
 		return
 	}
 
+	var session models.Session
+	database.DB.Where("token_hash = ?", hashRefreshToken(data.RefreshToken)).First(&session)
+
+	if session.ID == 0 || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: "Invalid refresh token"})
+		return
+	}
+
+	// A rotated token coming back means it was copied; sign out every device using the family
+	if session.RotatedAt != nil {
+		if err := revokeSessionFamily(database.DB, session.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not revoke session"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: "Refresh token reuse detected, session revoked"})
+		return
+	}
+
 	var user models.User
-	database.DB.Where("refresh_token = ?", data.RefreshToken).First(&user)
+	database.DB.First(&user, session.UserID)
 
 	if user.ID == 0 {
 		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: "Invalid refresh token"})
@@ -203,30 +236,101 @@ func RefreshToken(c *gin.Context) {
 	}
 
 	// Generate new access token
-	claims := AuthClaims{
-		Issuer: strconv.Itoa(int(user.ID)),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.LoadConfig("JWT_SECRET")))
+	tokenString, err := issueAccessToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not generate access token"})
 		return
 	}
 
-	// Generate new refresh token
-	newRefreshToken, err := generateRefreshToken()
+	// Rotate the refresh token
+	newRefreshToken, err := rotateSession(c, &session)
+	if errors.Is(err, errSessionRotated) {
+		if err := revokeSessionFamily(database.DB, session.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not revoke session"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: "Refresh token reuse detected, session revoked"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not generate new refresh token"})
 		return
 	}
 
-	user.RefreshToken = newRefreshToken
-	database.DB.Save(&user)
 	c.JSON(http.StatusOK, LoginResponse{
 		Token:        tokenString,
 		RefreshToken: newRefreshToken,
 	})
 }
+
+// @Summary Logout
+// @Description Revoke the access token used for the request, and the session of the refresh token when one is given.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   token    body    LogoutInput false "Refresh token of the session to end"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /auth/logout [post]
+func Logout(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var data LogoutInput
+	// The body is optional
+	_ = c.ShouldBindJSON(&data)
+
+	if jti := c.GetString("token_id"); jti != "" {
+		revoked := models.RevokedToken{
+			JTI:       jti,
+			UserID:    userID,
+			ExpiresAt: c.GetTime("token_expires_at"),
+		}
+		if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not logout"})
+			return
+		}
+	}
+
+	if data.RefreshToken != "" {
+		var session models.Session
+		database.DB.Where("token_hash = ? AND user_id = ?", hashRefreshToken(data.RefreshToken), userID).First(&session)
+		if session.ID != 0 {
+			if err := revokeSessionFamily(database.DB, session.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not logout"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Logged out"})
+}
+
+// pruneRevokedTokens deletes revoked tokens that have expired; they are
+// rejected anyway and only need to be kept until then.
+func pruneRevokedTokens() error {
+	return database.DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+// @Summary Get the token signing keys
+// @Description Get the public keys access tokens are signed with, as a JSON Web Key Set, so that other services can verify them. Empty when tokens are signed with HS256.
+// @Tags Auth
+// @Produce  json
+// @Success 200 {object} utils.JWKSet
+// @Failure 500 {object} models.MessageResponse
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	jwks, err := utils.PublicJWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not load signing keys"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
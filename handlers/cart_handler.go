@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pos/config"
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AddCartItemInput struct {
+	ProductID uint `json:"product_id" binding:"required,exists=products-id"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+type UpdateCartItemInput struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+type CheckoutCartInput struct {
+	PaymentMethod string         `json:"payment_method"`
+	Payments      []PaymentInput `json:"payments" binding:"omitempty,dive"`
+	RedeemPoints  int            `json:"redeem_points" binding:"omitempty,min=0"`
+}
+
+type CartResponse struct {
+	Cart  models.Cart       `json:"cart"`
+	Quote *utils.OrderQuote `json:"quote"`
+}
+
+// cartStockHoldEnabled reports whether cart items reserve stock via Product.ReservedQuantity.
+func cartStockHoldEnabled() bool {
+	return config.LoadConfig("CART_HOLD_STOCK") == "true"
+}
+
+// cartHoldTTL returns how long cart items hold stock after the cart last changed.
+func cartHoldTTL() time.Duration {
+	minutes, err := strconv.Atoi(config.LoadConfig("CART_HOLD_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// loadCart returns the user's cart with its items, creating an empty cart on
+// first use. The cart is locked for the rest of the transaction, and stock
+// holds that expired are released.
+func loadCart(db *gorm.DB, userID uint) (models.Cart, error) {
+	var cart models.Cart
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where(models.Cart{UserID: userID}).FirstOrCreate(&cart).Error; err != nil {
+		return cart, err
+	}
+	if err := db.Where("cart_id = ?", cart.ID).Order("id").Find(&cart.Items).Error; err != nil {
+		return cart, err
+	}
+
+	if cart.HoldExpiresAt != nil && !cart.HoldExpiresAt.After(time.Now()) {
+		if err := releaseCartHolds(db, &cart); err != nil {
+			return cart, err
+		}
+	}
+	return cart, nil
+}
+
+// releaseCartHolds puts the stock held by the cart's items back on sale. The
+// items stay in the cart without a hold.
+func releaseCartHolds(tx *gorm.DB, cart *models.Cart) error {
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		if item.HeldQuantity == 0 {
+			continue
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", item.ProductID).
+			Update("reserved_quantity", gorm.Expr("GREATEST(reserved_quantity - ?, 0)", item.HeldQuantity)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(item).Update("held_quantity", 0).Error; err != nil {
+			return err
+		}
+		item.HeldQuantity = 0
+	}
+
+	cart.HoldExpiresAt = nil
+	return tx.Model(cart).Update("hold_expires_at", nil).Error
+}
+
+// refreshCartHold restarts the hold period after the cart changed, or clears
+// it when none of its items hold stock.
+func refreshCartHold(tx *gorm.DB, cartID uint) error {
+	var held int64
+	if err := tx.Model(&models.CartItem{}).Where("cart_id = ? AND held_quantity > 0", cartID).Count(&held).Error; err != nil {
+		return err
+	}
+
+	var expiresAt *time.Time
+	if held > 0 {
+		at := time.Now().Add(cartHoldTTL())
+		expiresAt = &at
+	}
+	return tx.Model(&models.Cart{}).Where("id = ?", cartID).Update("hold_expires_at", expiresAt).Error
+}
+
+// releaseExpiredCartHolds releases the stock held by carts that were left
+// alone for longer than the hold period.
+func releaseExpiredCartHolds() error {
+	var userIDs []uint
+	if err := database.DB.Model(&models.Cart{}).Where("hold_expires_at <= ?", time.Now()).Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := loadCart(tx, userID)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cartQuoteItems(cart models.Cart) []utils.OrderQuoteItem {
+	items := make([]utils.OrderQuoteItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, utils.OrderQuoteItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return items
+}
+
+// setCartItemQuantity sets the item quantity and, when stock holds are enabled,
+// moves the difference in and out of the product's reserved quantity.
+func setCartItemQuantity(tx *gorm.DB, item *models.CartItem, quantity int) error {
+	held := 0
+	if cartStockHoldEnabled() {
+		held = quantity
+	}
+
+	if delta := held - item.HeldQuantity; delta != 0 {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, item.ProductID).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Product not found", Err: err}
+		}
+
+		if delta > 0 && product.Quantity-product.ReservedQuantity < delta {
+			return &httpError{Status: http.StatusBadRequest, Message: "Insufficient stock for product " + product.Name, Err: errors.New("not enough unreserved stock to hold")}
+		}
+
+		product.ReservedQuantity += delta
+		if product.ReservedQuantity < 0 {
+			product.ReservedQuantity = 0
+		}
+		if err := tx.Model(&product).Update("reserved_quantity", product.ReservedQuantity).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Failed to update reserved stock", Err: err}
+		}
+		item.HeldQuantity = held
+	}
+
+	item.Quantity = quantity
+	return nil
+}
+
+// respondCart reprices the cart and writes it to the response.
+func respondCart(c *gin.Context, status int, message string, cart models.Cart) {
+	response := CartResponse{Cart: cart}
+	if len(cart.Items) > 0 {
+		// Cart owners are users, not store customers, so the default price list applies
+		priceList, err := utils.ActivePriceList(database.DB, nil)
+		if err != nil {
+			respondOrderError(c, err)
+			return
+		}
+		quote, err := utils.QuoteOrder(database.DB, cartQuoteItems(cart), priceList)
+		if err != nil {
+			respondOrderError(c, err)
+			return
+		}
+		response.Quote = &quote
+	}
+	c.JSON(status, gin.H{"status": "success", "message": message, "data": response})
+}
+
+// GetCart handles fetching the current user's cart
+// @Summary Get the current user's cart
+// @Description Get the current user's cart, repriced with the active product and cart promotions. Stock holds that expired are released.
+// @Tags Cart
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /cart [get]
+func GetCart(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var cart models.Cart
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err = loadCart(tx, userID)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not load cart", "data": err.Error()})
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart fetched", cart)
+}
+
+// AddCartItem handles adding a product to the current user's cart
+// @Summary Add an item to the cart
+// @Description Add a product to the current user's cart. Adding a product already in the cart increases its quantity.
+// @Tags Cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   item    body    AddCartItemInput true "Item to add"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /cart/items [post]
+func AddCartItem(c *gin.Context) {
+	var input AddCartItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var cart models.Cart
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err = loadCart(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		item := models.CartItem{CartID: cart.ID, ProductID: input.ProductID}
+		for _, existing := range cart.Items {
+			if existing.ProductID == input.ProductID {
+				item = existing
+				break
+			}
+		}
+
+		if err := setCartItemQuantity(tx, &item, item.Quantity+input.Quantity); err != nil {
+			return err
+		}
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		if err := refreshCartHold(tx, cart.ID); err != nil {
+			return err
+		}
+
+		cart, err = loadCart(tx, userID)
+		return err
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart item added", cart)
+}
+
+// UpdateCartItem handles changing the quantity of a cart item
+// @Summary Update a cart item
+// @Description Set the quantity of an item in the current user's cart.
+// @Tags Cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Cart item ID"
+// @Param   item    body    UpdateCartItemInput true "New quantity"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /cart/items/{id} [patch]
+func UpdateCartItem(c *gin.Context) {
+	id := c.Param("id")
+	var input UpdateCartItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var cart models.Cart
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err = loadCart(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var item models.CartItem
+		if err := tx.Where("cart_id = ?", cart.ID).First(&item, id).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Cart item not found", Err: err}
+		}
+
+		if err := setCartItemQuantity(tx, &item, input.Quantity); err != nil {
+			return err
+		}
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		if err := refreshCartHold(tx, cart.ID); err != nil {
+			return err
+		}
+
+		cart, err = loadCart(tx, userID)
+		return err
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart item updated", cart)
+}
+
+// RemoveCartItem handles removing an item from the cart
+// @Summary Remove a cart item
+// @Description Remove an item from the current user's cart and release any stock it holds.
+// @Tags Cart
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Cart item ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /cart/items/{id} [delete]
+func RemoveCartItem(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var cart models.Cart
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err = loadCart(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var item models.CartItem
+		if err := tx.Where("cart_id = ?", cart.ID).First(&item, id).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Cart item not found", Err: err}
+		}
+
+		if err := setCartItemQuantity(tx, &item, 0); err != nil {
+			return err
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		if err := refreshCartHold(tx, cart.ID); err != nil {
+			return err
+		}
+
+		cart, err = loadCart(tx, userID)
+		return err
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart item removed", cart)
+}
+
+// ClearCart handles removing every item from the cart
+// @Summary Clear the cart
+// @Description Remove all items from the current user's cart and release any stock they hold.
+// @Tags Cart
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /cart [delete]
+func ClearCart(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var cart models.Cart
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err = loadCart(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := releaseCartHolds(tx, &cart); err != nil {
+			return err
+		}
+		if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+
+		cart.Items = nil
+		return nil
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	respondCart(c, http.StatusOK, "Cart cleared", cart)
+}
+
+// CheckoutCart handles converting the cart into an order
+// @Summary Check out the cart
+// @Description Convert the current user's cart into an order. Stock held by the cart is released and deducted as a sale, and the cart is emptied. Payment works as on order creation: only admins and cashiers can pay with payment_method or payments.
+// @Tags Cart
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   checkout body   CheckoutCartInput true "Checkout details"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /cart/checkout [post]
+func CheckoutCart(c *gin.Context) {
+	var input CheckoutCartInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	userID := user.ID
+
+	// Only staff can take manual payments; customers pay through payment intents
+	if (input.PaymentMethod != "" || len(input.Payments) > 0) && !isStaff(user) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Only admins and cashiers can record payments"})
+		return
+	}
+
+	order := models.Order{
+		UserID:        &userID,
+		Status:        models.OrderStatusPending,
+		PaymentStatus: models.PaymentStatusUnpaid,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err := loadCart(tx, userID)
+		if err != nil {
+			return err
+		}
+		if len(cart.Items) == 0 {
+			return &httpError{Status: http.StatusBadRequest, Message: "Cart is empty", Err: errors.New("no items to check out")}
+		}
+
+		// Release the cart's own holds so they don't block its own sale
+		if err := releaseCartHolds(tx, &cart); err != nil {
+			return err
+		}
+
+		if err := placeOrder(tx, &order, cartQuoteItems(cart), input.RedeemPoints); err != nil {
+			return err
+		}
+		if err := settleNewOrder(tx, &order, input.PaymentMethod, input.Payments, userID); err != nil {
+			return err
+		}
+
+		return tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Order created", "data": order})
+}
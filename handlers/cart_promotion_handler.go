@@ -12,11 +12,11 @@ import (
 )
 
 type CartPromotionInput struct {
-	PromotionType         string    `json:"promotion_type"` // e.g., "percentage_discount", "fixed_discount"
-	DiscountValue         float64   `json:"discount_value"`
-	MinimumPurchaseAmount float64   `json:"minimum_purchase_amount"`
-	StartDate             time.Time `json:"start_date"`
-	EndDate               time.Time `json:"end_date"`
+	PromotionType         string       `json:"promotion_type"` // e.g., "percentage_discount", "fixed_discount"
+	DiscountValue         models.Money `json:"discount_value"`
+	MinimumPurchaseAmount models.Money `json:"minimum_purchase_amount"`
+	StartDate             time.Time    `json:"start_date"`
+	EndDate               time.Time    `json:"end_date"`
 }
 
 type CartPromotionsResponse struct {
@@ -58,6 +58,11 @@ func CreateCartPromotion(c *gin.Context) {
 		return
 	}
 
+	if cartPromotion.PromotionType == "percentage_discount" && cartPromotion.DiscountValue > models.MoneyFromUnits(100) {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "DiscountValue must not exceed 100 for percentage_discount"})
+		return
+	}
+
 	if cartPromotion.MinimumPurchaseAmount <= 0 {
 		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "MinimumPurchaseAmount must be greater than 0"})
 		return
@@ -156,6 +161,11 @@ func UpdateCartPromotion(c *gin.Context) {
 		return
 	}
 
+	if existingCartPromotion.PromotionType == "percentage_discount" && existingCartPromotion.DiscountValue > models.MoneyFromUnits(100) {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "DiscountValue must not exceed 100 for percentage_discount"})
+		return
+	}
+
 	if existingCartPromotion.MinimumPurchaseAmount <= 0 {
 		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "MinimumPurchaseAmount must be greater than 0"})
 		return
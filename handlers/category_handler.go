@@ -11,7 +11,8 @@ import (
 )
 
 type CategoryInput struct {
-	Name string `json:"name" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	TaxClassID *uint  `json:"tax_class_id" binding:"omitempty,exists=tax_classes-id"`
 }
 
 type CategoriesResponse struct {
@@ -85,7 +86,8 @@ func StoreCategory(c *gin.Context) {
 	}
 
 	category := models.Category{
-		Name: data.Name,
+		Name:       data.Name,
+		TaxClassID: data.TaxClassID,
 	}
 
 	database.DB.Create(&category)
@@ -164,6 +166,7 @@ func UpdateCategoryByID(c *gin.Context) {
 	}
 
 	category.Name = data.Name
+	category.TaxClassID = data.TaxClassID
 
 	database.DB.Save(&category)
 
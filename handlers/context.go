@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"pos/database"
+	"pos/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentUserID returns the ID of the authenticated user set by middleware.Protected.
+func currentUserID(c *gin.Context) (uint, error) {
+	userIDStr := c.GetString("user_id")
+	if userIDStr == "" {
+		return 0, errors.New("user ID not found in context")
+	}
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid user ID format in context")
+	}
+	return uint(userID), nil
+}
+
+// currentUser loads the authenticated user set by middleware.Protected.
+func currentUser(c *gin.Context) (models.User, error) {
+	var user models.User
+	userID, err := currentUserID(c)
+	if err != nil {
+		return user, err
+	}
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return user, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// isStaff reports whether the user may act on behalf of other users.
+func isStaff(user models.User) bool {
+	return user.Role == models.RoleAdmin || user.Role == models.RoleCashier
+}
+
+// scopeOrders limits an orders query to the orders the user may see: admins
+// see every order, cashiers the orders they placed and their own, and other
+// users only their own.
+func scopeOrders(query *gorm.DB, user models.User) *gorm.DB {
+	switch user.Role {
+	case models.RoleAdmin:
+		return query
+	case models.RoleCashier:
+		return query.Where("(orders.user_id = ? OR orders.cashier_id = ?)", user.ID, user.ID)
+	default:
+		return query.Where("orders.user_id = ?", user.ID)
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"net/http"
+	"pos/database"
+	"pos/models"
+	"time"
+
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CustomerInput struct {
+	Name        string `json:"name" binding:"required"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	Phone       string `json:"phone"`
+	Address     string `json:"address"`
+	Tier        string `json:"tier" binding:"omitempty,oneof=bronze silver gold"`
+	PriceListID *uint  `json:"price_list_id" binding:"omitempty,exists=price_lists-id"` // Price list the customer buys at
+	Notes       string `json:"notes"`
+}
+
+type CustomerResponse struct {
+	Data models.Customer `json:"data"`
+}
+
+// CustomerHistory is a customer's purchase history. The totals only count
+// sold orders.
+type CustomerHistory struct {
+	Customer    models.Customer `json:"customer"`
+	OrderCount  int64           `json:"order_count"`
+	TotalSpent  models.Money    `json:"total_spent"`
+	LastOrderAt *time.Time      `json:"last_order_at"`
+	Orders      []models.Order  `json:"orders"`
+}
+
+// customerPhoneTaken reports whether another customer already uses phone.
+func customerPhoneTaken(phone string, excludeID any) (bool, error) {
+	if phone == "" {
+		return false, nil
+	}
+	return utils.IsDuplicate[models.Customer](database.DB, "phone", phone, excludeID)
+}
+
+// @Summary Get all customers
+// @Description Get a paginated list of customers, optionally searched by name, email or phone. Admin and cashier only.
+// @Tags Customers
+// @Produce  json
+// @Security BearerAuth
+// @Param   q         query    string  false        "Search by name, email or phone"
+// @Param   page      query    int     false        "Page number"
+// @Param   limit     query    int     false        "Number of customers per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} models.MessageResponse
+// @Router /customers [get]
+func GetCustomers(c *gin.Context) {
+	customers := []models.Customer{}
+	var total int64
+
+	page, _ := utils.GetInt(c.DefaultQuery("page", "1"))
+	limit, _ := utils.GetInt(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Customer{})
+	if q := c.Query("q"); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ? OR phone ILIKE ?", like, like, like)
+	}
+
+	query.Count(&total)
+	query.Order("name").Limit(limit).Offset(offset).Find(&customers)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  customers,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// @Summary Create a new customer
+// @Description Create a new customer record. Admin and cashier only.
+// @Tags Customers
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   customer body    CustomerInput true "Customer data"
+// @Success 201 {object} CustomerResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /customers [post]
+func StoreCustomer(c *gin.Context) {
+	var data CustomerInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	isDup, err := customerPhoneTaken(data.Phone, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Customer phone already exists"})
+		return
+	}
+
+	customer := models.Customer{
+		Name:        data.Name,
+		Email:       data.Email,
+		Phone:       data.Phone,
+		Address:     data.Address,
+		Tier:        data.Tier,
+		PriceListID: data.PriceListID,
+		Notes:       data.Notes,
+	}
+
+	if err := database.DB.Create(&customer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not create customer"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CustomerResponse{Data: customer})
+}
+
+// @Summary Get a customer by ID
+// @Description Get a single customer by its ID. Admin and cashier only.
+// @Tags Customers
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Customer ID"
+// @Success 200 {object} CustomerResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /customers/{id} [get]
+func GetCustomerByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var customer models.Customer
+	database.DB.First(&customer, id)
+
+	if customer.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, CustomerResponse{Data: customer})
+}
+
+// @Summary Update a customer by ID
+// @Description Update a customer by its ID. Admin and cashier only.
+// @Tags Customers
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Customer ID"
+// @Param   customer body    CustomerInput true "Customer data to update"
+// @Success 200 {object} CustomerResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /customers/{id} [put]
+func UpdateCustomerByID(c *gin.Context) {
+	id := c.Param("id")
+	var data CustomerInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	database.DB.First(&customer, id)
+
+	if customer.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Customer not found"})
+		return
+	}
+
+	isDup, err := customerPhoneTaken(data.Phone, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Customer phone already exists"})
+		return
+	}
+
+	customer.Name = data.Name
+	customer.Email = data.Email
+	customer.Phone = data.Phone
+	customer.Address = data.Address
+	customer.Tier = data.Tier
+	customer.PriceListID = data.PriceListID
+	customer.Notes = data.Notes
+	database.DB.Save(&customer)
+
+	c.JSON(http.StatusOK, CustomerResponse{Data: customer})
+}
+
+// @Summary Delete a customer by ID
+// @Description Delete a customer by its ID. Their orders are kept. Admin only.
+// @Tags Customers
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Customer ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /customers/{id} [delete]
+func DeleteCustomerByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var customer models.Customer
+	database.DB.First(&customer, id)
+
+	if customer.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Customer not found"})
+		return
+	}
+
+	database.DB.Delete(&customer, id)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Customer deleted"})
+}
+
+// @Summary Get a customer's purchase history
+// @Description Get the orders of a customer, newest first, with their order count, total spent and last purchase. Admin and cashier only.
+// @Tags Customers
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Customer ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /customers/{id}/orders [get]
+func GetCustomerOrders(c *gin.Context) {
+	id := c.Param("id")
+
+	history := CustomerHistory{Orders: []models.Order{}}
+	database.DB.First(&history.Customer, id)
+
+	if history.Customer.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Customer not found"})
+		return
+	}
+
+	var stats struct {
+		OrderCount  int64
+		TotalSpent  models.Money
+		LastOrderAt *time.Time
+	}
+	if err := database.DB.Model(&models.Order{}).
+		Select("COUNT(*) AS order_count, COALESCE(SUM(total_amount), 0) AS total_spent, MAX(created_at) AS last_order_at").
+		Where("customer_id = ? AND status NOT IN ?", history.Customer.ID, unsoldOrderStatuses).
+		Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	history.OrderCount = stats.OrderCount
+	history.TotalSpent = stats.TotalSpent
+	history.LastOrderAt = stats.LastOrderAt
+
+	if err := database.DB.Where("customer_id = ?", history.Customer.ID).
+		Preload("OrderItems.Product").Order("created_at DESC").Find(&history.Orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Customer history fetched", "data": history})
+}
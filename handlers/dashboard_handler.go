@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"pos/config"
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DashboardPeriod struct {
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	Revenue    models.Money `json:"revenue"`
+	Orders     int64        `json:"orders"`
+	BasketSize models.Money `json:"average_basket_size"` // Revenue / Orders
+	UnitsSold  int64        `json:"units_sold"`
+}
+
+type DashboardProduct struct {
+	ProductID uint         `json:"product_id"`
+	Name      string       `json:"name"`
+	UnitsSold int64        `json:"units_sold"`
+	Revenue   models.Money `json:"revenue"` // After item and cart discounts
+}
+
+type Dashboard struct {
+	GeneratedAt             time.Time          `json:"generated_at"`
+	Today                   DashboardPeriod    `json:"today"`
+	SameDayLastWeek         DashboardPeriod    `json:"same_day_last_week"` // Same day last week up to the same time of day
+	RevenueChangePct        *float64           `json:"revenue_change_pct"`
+	OrdersChangePct         *float64           `json:"orders_change_pct"`
+	BasketSizeChangePct     *float64           `json:"average_basket_size_change_pct"`
+	TopProducts             []DashboardProduct `json:"top_products"` // Top 10 products sold today by units
+	LowStockThreshold       int                `json:"low_stock_threshold"`
+	LowStockProducts        int64              `json:"low_stock_products"` // Products whose available stock is at or below the threshold
+	ActiveProductPromotions int64              `json:"active_product_promotions"`
+	ActiveCartPromotions    int64              `json:"active_cart_promotions"`
+}
+
+type DashboardResponse struct {
+	Data Dashboard `json:"data"`
+}
+
+var dashboardCache = utils.NewTTLCache[Dashboard]()
+
+// dashboardCacheTTL returns how long the dashboard is cached, set by DASHBOARD_CACHE_TTL_SECONDS (default 60).
+func dashboardCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(config.LoadConfig("DASHBOARD_CACHE_TTL_SECONDS"))
+	if err != nil || seconds < 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// lowStockThreshold returns the available quantity at or below which a product counts as low on stock.
+func lowStockThreshold() int {
+	threshold, err := strconv.Atoi(config.LoadConfig("LOW_STOCK_THRESHOLD"))
+	if err != nil || threshold < 0 {
+		return 10
+	}
+	return threshold
+}
+
+// dashboardPeriodStats fills the revenue, order count, basket size and units sold of orders placed in the period.
+func dashboardPeriodStats(period *DashboardPeriod) error {
+	if err := database.DB.Model(&models.Order{}).
+		Select("COUNT(*) AS orders, COALESCE(SUM(total_amount), 0) AS revenue").
+		Where("status NOT IN ?", unsoldOrderStatuses).
+		Where("created_at >= ? AND created_at < ?", period.From, period.To).
+		Scan(period).Error; err != nil {
+		return err
+	}
+	if period.Orders > 0 {
+		period.BasketSize = period.Revenue.MulRat(1, period.Orders, models.ConfiguredRounding())
+	}
+	return soldItems(PromotionPeriodStats{From: period.From, To: period.To}).
+		Select("COALESCE(SUM(order_items.quantity), 0)").
+		Scan(&period.UnitsSold).Error
+}
+
+func buildDashboard() (Dashboard, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	dashboard := Dashboard{
+		GeneratedAt:       now,
+		Today:             DashboardPeriod{From: startOfDay, To: now},
+		SameDayLastWeek:   DashboardPeriod{From: startOfDay.AddDate(0, 0, -7), To: now.AddDate(0, 0, -7)},
+		TopProducts:       []DashboardProduct{},
+		LowStockThreshold: lowStockThreshold(),
+	}
+
+	if err := dashboardPeriodStats(&dashboard.Today); err != nil {
+		return dashboard, err
+	}
+	if err := dashboardPeriodStats(&dashboard.SameDayLastWeek); err != nil {
+		return dashboard, err
+	}
+	dashboard.RevenueChangePct = changePct(dashboard.SameDayLastWeek.Revenue.Float64(), dashboard.Today.Revenue.Float64())
+	dashboard.OrdersChangePct = changePct(float64(dashboard.SameDayLastWeek.Orders), float64(dashboard.Today.Orders))
+	dashboard.BasketSizeChangePct = changePct(dashboard.SameDayLastWeek.BasketSize.Float64(), dashboard.Today.BasketSize.Float64())
+
+	if err := soldItems(PromotionPeriodStats{From: dashboard.Today.From, To: dashboard.Today.To}).
+		Joins("LEFT JOIN products ON products.id = order_items.product_id").
+		Select("order_items.product_id, COALESCE(MAX(products.name), '') AS name, SUM(order_items.quantity) AS units_sold, SUM(order_items.discounted_price - order_items.cart_discount_share) AS revenue").
+		Group("order_items.product_id").
+		Order("units_sold DESC, revenue DESC").
+		Limit(10).
+		Scan(&dashboard.TopProducts).Error; err != nil {
+		return dashboard, err
+	}
+
+	if err := database.DB.Model(&models.Product{}).
+		Where("quantity - reserved_quantity <= ?", dashboard.LowStockThreshold).
+		Count(&dashboard.LowStockProducts).Error; err != nil {
+		return dashboard, err
+	}
+
+	if err := database.DB.Model(&models.ProductPromotion{}).
+		Where("start_date <= ? AND end_date >= ?", now, now).
+		Count(&dashboard.ActiveProductPromotions).Error; err != nil {
+		return dashboard, err
+	}
+	if err := database.DB.Model(&models.CartPromotion{}).
+		Where("start_date <= ? AND end_date >= ?", now, now).
+		Count(&dashboard.ActiveCartPromotions).Error; err != nil {
+		return dashboard, err
+	}
+
+	return dashboard, nil
+}
+
+// GetDashboard handles fetching the store KPIs
+// @Summary Get dashboard KPIs
+// @Description Get today's revenue, order count, average basket size and top 10 products compared with the same day last week, plus the low-stock product count and active promotions. Figures are cached for DASHBOARD_CACHE_TTL_SECONDS. Admin only.
+// @Tags Dashboard
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} DashboardResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /dashboard [get]
+func GetDashboard(c *gin.Context) {
+	if dashboard, ok := dashboardCache.Get("dashboard"); ok {
+		c.JSON(http.StatusOK, DashboardResponse{Data: dashboard})
+		return
+	}
+
+	dashboard, err := buildDashboard()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not build dashboard"})
+		return
+	}
+	dashboardCache.Set("dashboard", dashboard, dashboardCacheTTL())
+
+	c.JSON(http.StatusOK, DashboardResponse{Data: dashboard})
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReorderSuggestion struct {
+	ProductID   uint    `json:"product_id"`
+	Name        string  `json:"name"`
+	SKU         string  `json:"sku"`
+	OnHand      int     `json:"on_hand"`
+	Available   int     `json:"available"`    // On hand minus stock held by carts
+	DailyDemand float64 `json:"daily_demand"` // Forecast units sold per day
+	utils.ReorderPlan
+}
+
+type ReorderSuggestionsReport struct {
+	Method       string              `json:"method"` // "ema" or "sma"
+	HistoryDays  int                 `json:"history_days"`
+	LeadTimeDays int                 `json:"lead_time_days"`
+	CoverDays    int                 `json:"cover_days"`
+	SafetyDays   int                 `json:"safety_days"`
+	Products     []ReorderSuggestion `json:"products"`
+}
+
+type ReorderSuggestionsResponse struct {
+	Data ReorderSuggestionsReport `json:"data"`
+}
+
+type InventoryAnalysisRow struct {
+	ProductID         uint         `json:"product_id"`
+	Name              string       `json:"name"`
+	SKU               string       `json:"sku"`
+	Class             string       `json:"class"` // "A", "B" or "C"
+	OnHand            int          `json:"on_hand"`
+	UnitsSold         int64        `json:"units_sold"`        // Net of returns
+	Revenue           models.Money `json:"revenue"`           // Units sold at the current price
+	RevenueSharePct   float64      `json:"revenue_share_pct"` // Share of the revenue of all products
+	CumulativePct     float64      `json:"cumulative_pct"`
+	AverageStock      float64      `json:"average_stock"`  // Average of the stock at the start and end of the period
+	StockTurnover     *float64     `json:"stock_turnover"` // Units sold / average stock; nil without stock
+	LastSoldAt        *time.Time   `json:"last_sold_at"`
+	DaysSinceLastSale *int         `json:"days_since_last_sale"` // nil when the product was never sold
+}
+
+type InventoryAnalysis struct {
+	From     time.Time              `json:"from"`
+	To       time.Time              `json:"to"`
+	Products []InventoryAnalysisRow `json:"products"`
+}
+
+type InventoryAnalysisResponse struct {
+	Data InventoryAnalysis `json:"data"`
+}
+
+type DeadStockReport struct {
+	Days     int                    `json:"days"`
+	Products []InventoryAnalysisRow `json:"products"`
+}
+
+type DeadStockResponse struct {
+	Data DeadStockReport `json:"data"`
+}
+
+// queryIntAtLeast reads an integer query parameter that must be at least min.
+func queryIntAtLeast(c *gin.Context, key string, fallback, min int) (int, bool) {
+	value, err := utils.GetInt(c.DefaultQuery(key, strconv.Itoa(fallback)))
+	return value, err == nil && value >= min
+}
+
+// positiveQueryInt reads a query parameter that must be a positive integer.
+func positiveQueryInt(c *gin.Context, key string, fallback int) (int, bool) {
+	return queryIntAtLeast(c, key, fallback, 1)
+}
+
+// dailySales returns the units sold per product per day over the last days,
+// oldest day first: the sale stock transactions minus the returns of
+// cancelled, refunded and edited orders. Days are cut in the database time
+// zone, like the timestamps they are compared with.
+func dailySales(days int) (map[uint][]float64, error) {
+	var rows []struct {
+		ProductID uint
+		Day       int // Days since the first day of the history
+		Quantity  int64
+	}
+	if err := database.DB.Model(&models.StockTransaction{}).
+		Select("product_id, CAST(created_at AS date) - (CURRENT_DATE - CAST(? AS integer)) AS day, SUM(CASE WHEN sub_type = ? THEN quantity ELSE -quantity END) AS quantity",
+			days-1, models.SubTypeSale).
+		Where("sub_type IN ? AND created_at >= CURRENT_DATE - CAST(? AS integer)",
+			[]models.StockTransactionSubType{models.SubTypeSale, models.SubTypeReturn}, days-1).
+		Group("product_id, CAST(created_at AS date)").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	sales := map[uint][]float64{}
+	for _, row := range rows {
+		series, ok := sales[row.ProductID]
+		if !ok {
+			series = make([]float64, days)
+			sales[row.ProductID] = series
+		}
+		if row.Day >= 0 && row.Day < days {
+			series[row.Day] += float64(row.Quantity)
+		}
+	}
+	return sales, nil
+}
+
+// GetReorderSuggestions handles forecasting demand and suggesting reorders
+// @Summary Get reorder suggestions
+// @Description Forecast the daily demand of each product from its sale stock transactions, net of returns, with exponential smoothing (ema) or a moving average (sma), estimate the days of stock remaining and suggest how much to reorder. By default only products that need reordering are returned. Admin only.
+// @Tags Reports
+// @Produce  json
+// @Security BearerAuth
+// @Param   method          query   string  false   "ema (default) or sma"
+// @Param   alpha           query   number  false   "Smoothing factor for ema, 0-1 (default 0.3)"
+// @Param   window          query   int     false   "Days averaged by sma (default 7)"
+// @Param   history_days    query   int     false   "Days of sales history to use (default 56)"
+// @Param   lead_time_days  query   int     false   "Days between ordering and receiving stock (default 7)"
+// @Param   cover_days      query   int     false   "Days a reorder should last after it arrives (default 14)"
+// @Param   safety_days     query   int     false   "Days of demand kept as safety stock, 0 for none (default 3)"
+// @Param   all             query   bool    false   "Include products that do not need reordering"
+// @Success 200 {object} ReorderSuggestionsResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /reports/reorder-suggestions [get]
+func GetReorderSuggestions(c *gin.Context) {
+	report := ReorderSuggestionsReport{Method: c.DefaultQuery("method", "ema"), Products: []ReorderSuggestion{}}
+	if report.Method != "ema" && report.Method != "sma" {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid method. Must be 'ema' or 'sma'"})
+		return
+	}
+
+	alpha, err := strconv.ParseFloat(c.DefaultQuery("alpha", "0.3"), 64)
+	if err != nil || alpha <= 0 || alpha > 1 {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "alpha must be greater than 0 and at most 1"})
+		return
+	}
+
+	var window int
+	var ok bool
+	for _, param := range []struct {
+		key      string
+		fallback int
+		min      int
+		target   *int
+	}{
+		{"window", 7, 1, &window},
+		{"history_days", 56, 1, &report.HistoryDays},
+		{"lead_time_days", 7, 1, &report.LeadTimeDays},
+		{"cover_days", 14, 1, &report.CoverDays},
+		{"safety_days", 3, 0, &report.SafetyDays},
+	} {
+		if *param.target, ok = queryIntAtLeast(c, param.key, param.fallback, param.min); !ok {
+			message := param.key + " must be a positive number"
+			if param.min == 0 {
+				message = param.key + " must be zero or a positive number"
+			}
+			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: message})
+			return
+		}
+	}
+
+	sales, err := dailySales(report.HistoryDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not load sales history"})
+		return
+	}
+
+	var products []models.Product
+	if err := database.DB.Order("name").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+
+	includeAll := c.Query("all") == "true"
+	for _, product := range products {
+		series := sales[product.ID]
+		demand := utils.ExponentialSmoothing(series, alpha)
+		if report.Method == "sma" {
+			demand = utils.MovingAverage(series, window)
+		}
+		// Returns can outweigh the sales of a day, but demand never goes below zero
+		demand = math.Max(math.Round(demand*100)/100, 0)
+
+		suggestion := ReorderSuggestion{
+			ProductID:   product.ID,
+			Name:        product.Name,
+			SKU:         product.SKU,
+			OnHand:      product.Quantity,
+			Available:   product.Quantity - product.ReservedQuantity,
+			DailyDemand: demand,
+		}
+		suggestion.ReorderPlan = utils.PlanReorder(demand, suggestion.Available, report.LeadTimeDays, report.CoverDays, report.SafetyDays)
+		if suggestion.NeedsReorder || includeAll {
+			report.Products = append(report.Products, suggestion)
+		}
+	}
+
+	// Products that run out first come first; products without demand come last
+	sort.SliceStable(report.Products, func(i, j int) bool {
+		a, b := report.Products[i].DaysOfStock, report.Products[j].DaysOfStock
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	c.JSON(http.StatusOK, ReorderSuggestionsResponse{Data: report})
+}
+
+// analyseInventory classifies the products by their share of the revenue sold
+// in the period (A up to aLimit percent of cumulative revenue, B up to bLimit,
+// C the rest) and works out their stock turnover and last sale. It is based on
+// the sale stock transactions net of returns, valued at the current product price.
+func analyseInventory(from, to time.Time, aLimit, bLimit float64) ([]InventoryAnalysisRow, error) {
+	var products []models.Product
+	if err := database.DB.Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	var movements []struct {
+		ProductID   uint
+		UnitsSold   int64
+		NetInPeriod int64 // Stock in minus stock out during the period
+		NetAfter    int64 // Stock in minus stock out after the period
+	}
+	if err := database.DB.Model(&models.StockTransaction{}).
+		Select(`product_id,
+			COALESCE(SUM(CASE WHEN created_at >= ? AND created_at < ? THEN CASE sub_type WHEN ? THEN quantity WHEN ? THEN -quantity ELSE 0 END ELSE 0 END), 0) AS units_sold,
+			COALESCE(SUM(CASE WHEN created_at >= ? AND created_at < ? THEN CASE WHEN type = ? THEN quantity ELSE -quantity END ELSE 0 END), 0) AS net_in_period,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN CASE WHEN type = ? THEN quantity ELSE -quantity END ELSE 0 END), 0) AS net_after`,
+			from, to, models.SubTypeSale, models.SubTypeReturn,
+			from, to, models.StockTransactionTypeIn,
+			to, models.StockTransactionTypeIn).
+		Group("product_id").
+		Scan(&movements).Error; err != nil {
+		return nil, err
+	}
+
+	var lastSales []struct {
+		ProductID  uint
+		LastSoldAt time.Time
+	}
+	// Returns cancel the latest sales before them, so the last sale is the
+	// newest one that is not outweighed by the returns after it
+	if err := database.DB.Raw(`SELECT product_id, MAX(created_at) AS last_sold_at FROM (
+			SELECT product_id, created_at, sub_type, quantity,
+				COALESCE(SUM(CASE WHEN sub_type = ? THEN quantity ELSE -quantity END) OVER (
+					PARTITION BY product_id ORDER BY created_at DESC, id DESC
+					ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING), 0) AS returned_after
+			FROM stock_transactions
+			WHERE deleted_at IS NULL AND sub_type IN ?
+		) movements
+		WHERE sub_type = ? AND quantity > returned_after
+		GROUP BY product_id`,
+		models.SubTypeReturn, []models.StockTransactionSubType{models.SubTypeSale, models.SubTypeReturn}, models.SubTypeSale).
+		Scan(&lastSales).Error; err != nil {
+		return nil, err
+	}
+	lastSold := map[uint]time.Time{}
+	for _, sale := range lastSales {
+		lastSold[sale.ProductID] = sale.LastSoldAt
+	}
+
+	rows := make([]InventoryAnalysisRow, 0, len(products))
+	var totalRevenue models.Money
+	for _, product := range products {
+		row := InventoryAnalysisRow{ProductID: product.ID, Name: product.Name, SKU: product.SKU, OnHand: product.Quantity}
+
+		// Stock did not move unless a stock transaction says otherwise
+		row.AverageStock = float64(product.Quantity)
+		for _, movement := range movements {
+			if movement.ProductID == product.ID {
+				row.UnitsSold = max(movement.UnitsSold, 0)
+				closing := float64(product.Quantity - int(movement.NetAfter))
+				opening := closing - float64(movement.NetInPeriod)
+				row.AverageStock = (opening + closing) / 2
+				break
+			}
+		}
+		if row.AverageStock > 0 {
+			turnover := math.Round(float64(row.UnitsSold)/row.AverageStock*100) / 100
+			row.StockTurnover = &turnover
+		}
+
+		if soldAt, ok := lastSold[product.ID]; ok {
+			days := int(time.Since(soldAt).Hours() / 24)
+			row.LastSoldAt = &soldAt
+			row.DaysSinceLastSale = &days
+		}
+
+		row.Revenue = product.Price.MulInt(int(row.UnitsSold))
+		totalRevenue += row.Revenue
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Revenue != rows[j].Revenue {
+			return rows[i].Revenue > rows[j].Revenue
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	var cumulative models.Money
+	for i := range rows {
+		row := &rows[i]
+		row.Class = "C"
+		if totalRevenue <= 0 || row.Revenue <= 0 {
+			continue
+		}
+		// A product is classed by the cumulative share before it, so the top seller is always A
+		previousPct := cumulative.Float64() / totalRevenue.Float64() * 100
+		cumulative += row.Revenue
+		row.RevenueSharePct = math.Round(row.Revenue.Float64()/totalRevenue.Float64()*10000) / 100
+		row.CumulativePct = math.Round(cumulative.Float64()/totalRevenue.Float64()*10000) / 100
+		switch {
+		case previousPct < aLimit:
+			row.Class = "A"
+		case previousPct < bLimit:
+			row.Class = "B"
+		}
+	}
+	return rows, nil
+}
+
+// inventoryCSV lays out inventory analysis rows as CSV records.
+func inventoryCSV(rows []InventoryAnalysisRow) [][]string {
+	records := [][]string{{"product_id", "name", "sku", "class", "on_hand", "units_sold", "revenue", "revenue_share_pct", "cumulative_pct", "average_stock", "stock_turnover", "last_sold_at", "days_since_last_sale"}}
+	for _, row := range rows {
+		turnover, lastSoldAt, daysSince := "", "", ""
+		if row.StockTurnover != nil {
+			turnover = strconv.FormatFloat(*row.StockTurnover, 'f', 2, 64)
+		}
+		if row.LastSoldAt != nil {
+			lastSoldAt = row.LastSoldAt.Format(time.RFC3339)
+			daysSince = strconv.Itoa(*row.DaysSinceLastSale)
+		}
+		records = append(records, []string{
+			strconv.FormatUint(uint64(row.ProductID), 10),
+			row.Name,
+			row.SKU,
+			row.Class,
+			strconv.Itoa(row.OnHand),
+			strconv.FormatInt(row.UnitsSold, 10),
+			row.Revenue.String(),
+			strconv.FormatFloat(row.RevenueSharePct, 'f', 2, 64),
+			strconv.FormatFloat(row.CumulativePct, 'f', 2, 64),
+			strconv.FormatFloat(row.AverageStock, 'f', 1, 64),
+			turnover,
+			lastSoldAt,
+			daysSince,
+		})
+	}
+	return records
+}
+
+// GetInventoryAnalysis handles the ABC analysis of the products
+// @Summary Get ABC inventory analysis
+// @Description Classify products by their contribution to the revenue sold in the date range: A products make up the first a_pct percent of cumulative revenue, B the next part up to b_pct, C the rest. Revenue is the units sold according to the sale stock transactions, less returns, at the current price. Each product also gets its stock turnover (units sold / average stock). Admin only.
+// @Tags Reports
+// @Produce  json
+// @Produce  text/csv
+// @Security BearerAuth
+// @Param   from    query   string  false       "Start date (YYYY-MM-DD), defaults to the first day of the current month"
+// @Param   to      query   string  false       "End date, inclusive (YYYY-MM-DD), defaults to today"
+// @Param   a_pct   query   number  false       "Cumulative revenue share of class A (default 80)"
+// @Param   b_pct   query   number  false       "Cumulative revenue share of classes A and B (default 95)"
+// @Param   format  query   string  false       "json (default) or csv"
+// @Success 200 {object} InventoryAnalysisResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /reports/inventory-abc [get]
+func GetInventoryAnalysis(c *gin.Context) {
+	from, to, err := reportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid date range. Use YYYY-MM-DD"})
+		return
+	}
+
+	aLimit, errA := strconv.ParseFloat(c.DefaultQuery("a_pct", "80"), 64)
+	bLimit, errB := strconv.ParseFloat(c.DefaultQuery("b_pct", "95"), 64)
+	if errA != nil || errB != nil || aLimit <= 0 || bLimit < aLimit || bLimit > 100 {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "a_pct and b_pct must satisfy 0 < a_pct <= b_pct <= 100"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid format. Must be 'json' or 'csv'"})
+		return
+	}
+
+	rows, err := analyseInventory(from, to, aLimit, bLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not build inventory analysis"})
+		return
+	}
+
+	if format == "csv" {
+		writeCSV(c, fmt.Sprintf("inventory-abc-%s-%s.csv", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")), inventoryCSV(rows))
+		return
+	}
+	c.JSON(http.StatusOK, InventoryAnalysisResponse{Data: InventoryAnalysis{From: from, To: to, Products: rows}})
+}
+
+// GetDeadStock handles listing products that stopped selling
+// @Summary Get dead stock
+// @Description List products with stock on hand and no sale stock transactions in the last days that were not returned again, with their ABC class and stock turnover over that window. Admin only.
+// @Tags Reports
+// @Produce  json
+// @Produce  text/csv
+// @Security BearerAuth
+// @Param   days    query   int     false       "Days without a sale (default 90)"
+// @Param   format  query   string  false       "json (default) or csv"
+// @Success 200 {object} DeadStockResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /reports/dead-stock [get]
+func GetDeadStock(c *gin.Context) {
+	days, ok := positiveQueryInt(c, "days", 90)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "days must be a positive number"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid format. Must be 'json' or 'csv'"})
+		return
+	}
+
+	to := time.Now()
+	rows, err := analyseInventory(to.AddDate(0, 0, -days), to, 80, 95)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not build dead stock report"})
+		return
+	}
+
+	report := DeadStockReport{Days: days, Products: []InventoryAnalysisRow{}}
+	for _, row := range rows {
+		if row.OnHand > 0 && (row.DaysSinceLastSale == nil || *row.DaysSinceLastSale >= days) {
+			report.Products = append(report.Products, row)
+		}
+	}
+
+	if format == "csv" {
+		writeCSV(c, fmt.Sprintf("dead-stock-%dd.csv", days), inventoryCSV(report.Products))
+		return
+	}
+	c.JSON(http.StatusOK, DeadStockResponse{Data: report})
+}
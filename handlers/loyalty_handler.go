@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LoyaltyResponse struct {
+	Balance      int                         `json:"balance"`
+	PointValue   models.Money                `json:"point_value"`
+	Transactions []models.LoyaltyTransaction `json:"transactions"`
+	Total        int64                       `json:"total"`
+	Page         int                         `json:"page"`
+	Limit        int                         `json:"limit"`
+}
+
+// respondLoyalty writes the user's points balance and a page of their points ledger.
+func respondLoyalty(c *gin.Context, userID uint) {
+	page, _ := utils.GetInt(c.DefaultQuery("page", "1"))
+	limit, _ := utils.GetInt(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	response := LoyaltyResponse{PointValue: utils.LoyaltyPointValue(), Page: page, Limit: limit}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if response.Balance, err = utils.LoyaltyBalance(tx, userID); err != nil {
+			return err
+		}
+
+		query := tx.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", userID)
+		if err := query.Count(&response.Total).Error; err != nil {
+			return err
+		}
+		return query.Order("id DESC").Limit(limit).Offset(offset).Find(&response.Transactions).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// @Summary Get loyalty points
+// @Description Get the points balance and points ledger of the currently logged-in user. Expired points are written off first.
+// @Tags Profile
+// @Produce  json
+// @Security BearerAuth
+// @Param   page      query    int     false        "Page number"
+// @Param   limit     query    int     false        "Number of items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /profile/loyalty [get]
+func GetProfileLoyalty(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": err.Error()})
+		return
+	}
+
+	respondLoyalty(c, userID)
+}
+
+// @Summary Get a user's loyalty points
+// @Description Get the points balance and points ledger of a user by their ID. Admin only.
+// @Tags Users
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "User ID"
+// @Param   page      query    int     false        "Page number"
+// @Param   limit     query    int     false        "Number of items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /users/{id}/loyalty [get]
+func GetUserLoyalty(c *gin.Context) {
+	id := c.Param("id")
+
+	var user models.User
+	database.DB.First(&user, id)
+
+	if user.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "User not found",
+		})
+		return
+	}
+
+	respondLoyalty(c, user.ID)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pos/middleware"
+)
+
+// RunMaintenance periodically does the work requests leave behind, like
+// retrying gateway refunds that failed, releasing the stock held by
+// abandoned carts and pruning expired revoked tokens and idempotency keys. It blocks, so run it in a goroutine.
+func RunMaintenance(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := refundPendingIntents(context.Background(), 0); err != nil {
+			log.Printf("maintenance: pending refunds: %v", err)
+		}
+		if err := releaseExpiredCartHolds(); err != nil {
+			log.Printf("maintenance: cart holds: %v", err)
+		}
+		if err := pruneRevokedTokens(); err != nil {
+			log.Printf("maintenance: revoked tokens: %v", err)
+		}
+		if err := middleware.PruneIdempotencyKeys(); err != nil {
+			log.Printf("maintenance: idempotency keys: %v", err)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderItemEditInput struct {
+	ProductID uint `json:"product_id" binding:"required,exists=products-id"`
+	Quantity  int  `json:"quantity" binding:"min=0"` // New quantity of the line; 0 removes it
+}
+
+type EditOrderItemsInput struct {
+	Items []OrderItemEditInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// adjustOrderStock moves delta units of a product out of stock (delta > 0) or
+// back into it (delta < 0) for an edit of order, logging the stock transaction.
+func adjustOrderStock(tx *gorm.DB, order *models.Order, productID uint, delta int, userID uint) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+		return &httpError{Status: http.StatusNotFound, Message: "Product not found", Err: err}
+	}
+
+	stockTransaction := models.StockTransaction{
+		ProductID: product.ID,
+		UserID:    userID,
+		Notes:     fmt.Sprintf("Edit of order %d", order.ID),
+	}
+	if delta > 0 {
+		// Check stock availability, leaving units held by carts untouched
+		if product.Quantity-product.ReservedQuantity < delta {
+			return &httpError{Status: http.StatusBadRequest, Message: "Insufficient stock for product " + product.Name, Err: fmt.Errorf("%d available", product.Quantity-product.ReservedQuantity)}
+		}
+		stockTransaction.Quantity = delta
+		stockTransaction.Type = models.StockTransactionTypeOut
+		stockTransaction.SubType = models.SubTypeSale
+	} else {
+		stockTransaction.Quantity = -delta
+		stockTransaction.Type = models.StockTransactionTypeIn
+		stockTransaction.SubType = models.SubTypeReturn
+	}
+
+	product.Quantity -= delta
+	if err := tx.Save(&product).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Failed to update stock", Err: err}
+	}
+	if err := tx.Create(&stockTransaction).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Failed to create stock transaction", Err: err}
+	}
+	return nil
+}
+
+// EditOrderItems handles changing the items of a pending order
+// @Summary Edit the items of an order
+// @Description Add, remove or change the quantity of lines on a pending order that has not taken any payment. Each listed product is set to the given quantity (0 removes it) and unlisted lines are kept. The order is re-priced with the current promotions, stock is adjusted and every change is recorded in the order history.
+// @Tags Orders
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Param   items   body    EditOrderItemsInput true "Item changes"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/items [patch]
+func EditOrderItems(c *gin.Context) {
+	id := c.Param("id")
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input EditOrderItemsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	var order models.Order
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := scopeOrders(tx, user).Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").First(&order, id).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Order not found", Err: err}
+		}
+		if order.Status != models.OrderStatusPending || order.PaymentStatus != models.PaymentStatusUnpaid {
+			return &httpError{Status: http.StatusBadRequest, Message: "Only pending orders without payments can be edited", Err: fmt.Errorf("order is %s and %s", order.Status, order.PaymentStatus)}
+		}
+		var openIntents int64
+		if err := tx.Model(&models.PaymentIntent{}).
+			Where("order_id = ? AND status = ?", order.ID, models.PaymentIntentRequiresCapture).
+			Count(&openIntents).Error; err != nil {
+			return err
+		}
+		if openIntents > 0 {
+			return &httpError{Status: http.StatusBadRequest, Message: "Orders with a payment in progress cannot be edited", Err: fmt.Errorf("%d payment intents awaiting capture", openIntents)}
+		}
+
+		// Free items are granted by promotions and were never taken from stock
+		oldQuantities := map[uint]int{}
+		for _, item := range order.OrderItems {
+			if !item.IsFreeItem {
+				oldQuantities[item.ProductID] += item.Quantity
+			}
+		}
+		newQuantities := map[uint]int{}
+		for productID, quantity := range oldQuantities {
+			newQuantities[productID] = quantity
+		}
+		for _, item := range input.Items {
+			if item.Quantity == 0 {
+				delete(newQuantities, item.ProductID)
+			} else {
+				newQuantities[item.ProductID] = item.Quantity
+			}
+		}
+		if len(newQuantities) == 0 {
+			return &httpError{Status: http.StatusBadRequest, Message: "An order needs at least one item; cancel it instead", Err: errors.New("all items removed")}
+		}
+
+		productIDs := make([]uint, 0, len(newQuantities))
+		for productID := range newQuantities {
+			productIDs = append(productIDs, productID)
+		}
+		sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+		items := make([]utils.OrderQuoteItem, 0, len(productIDs))
+		for _, productID := range productIDs {
+			items = append(items, utils.OrderQuoteItem{ProductID: productID, Quantity: newQuantities[productID]})
+		}
+
+		// Re-price the order with the promotions and prices active now
+		priceList, err := utils.ActivePriceList(tx, order.CustomerID)
+		if err != nil {
+			return err
+		}
+		quote, err := utils.QuoteOrder(tx, items, priceList)
+		if err != nil {
+			return err
+		}
+
+		var history []models.OrderHistory
+		for _, change := range input.Items {
+			oldQuantity, newQuantity := oldQuantities[change.ProductID], newQuantities[change.ProductID]
+			if oldQuantity == newQuantity {
+				continue
+			}
+			if err := adjustOrderStock(tx, &order, change.ProductID, newQuantity-oldQuantity, user.ID); err != nil {
+				return err
+			}
+			// Later entries for the same product apply on top of this one
+			oldQuantities[change.ProductID] = newQuantity
+
+			action := models.OrderHistoryQuantityChange
+			switch {
+			case oldQuantity == 0:
+				action = models.OrderHistoryItemAdded
+			case newQuantity == 0:
+				action = models.OrderHistoryItemRemoved
+			}
+			history = append(history, models.OrderHistory{
+				OrderID:     order.ID,
+				UserID:      user.ID,
+				Action:      action,
+				ProductID:   change.ProductID,
+				OldQuantity: oldQuantity,
+				NewQuantity: newQuantity,
+				OldTotal:    order.TotalAmount,
+			})
+		}
+		if len(history) == 0 {
+			return &httpError{Status: http.StatusBadRequest, Message: "No changes to the order items", Err: errors.New("quantities unchanged")}
+		}
+
+		if err := tx.Unscoped().Where("order_id = ?", order.ID).Delete(&models.OrderItem{}).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not remove order items", Err: err}
+		}
+		if err := tx.Unscoped().Where("order_id = ?", order.ID).Delete(&models.OrderTax{}).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not remove order taxes", Err: err}
+		}
+		if err := storeOrderQuote(tx, &order, quote); err != nil {
+			return err
+		}
+
+		// Points redeemed when the order was placed keep discounting it, as long
+		// as the new total still needs all of them
+		order.PointsDiscount = 0
+		if order.PointsRedeemed > 0 {
+			points, discount := utils.PointsDiscount(order.PointsRedeemed, order.TotalAmount)
+			if points < order.PointsRedeemed {
+				return &httpError{Status: http.StatusBadRequest, Message: "The new total is too low for the loyalty points redeemed on the order", Err: fmt.Errorf("%d of %d points needed", points, order.PointsRedeemed)}
+			}
+			order.PointsDiscount = discount
+			order.TotalAmount -= discount
+		}
+
+		for i := range history {
+			history[i].NewTotal = order.TotalAmount
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not record order history", Err: err}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not update order totals", Err: err}
+		}
+		return tx.Where("order_id = ?", order.ID).Order("id").Find(&order.History).Error
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Order updated", "data": order})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,20 +11,248 @@ import (
 	"pos/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+type OrderItemInput struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
 type CreateOrderInput struct {
-	PaymentMethod string `json:"payment_method" binding:"required"`
-	UserID        uint   `json:"user_id" binding:"required"`
-	Items         []struct {
-		ProductID uint `json:"product_id" binding:"required"`
-		Quantity  int  `json:"quantity" binding:"required,min=1"`
-	} `json:"items" binding:"required,min=1"`
+	PaymentMethod string           `json:"payment_method"`                                      // Pays the whole order with a single method when Payments is empty
+	Payments      []PaymentInput   `json:"payments" binding:"omitempty,dive"`                   // Split payments; must add up to the order total
+	UserID        uint             `json:"user_id" binding:"omitempty,exists=users-id"`         // User account the order is for; only admins and cashiers can set it, everyone else orders for themselves
+	CustomerID    *uint            `json:"customer_id" binding:"omitempty,exists=customers-id"` // Store customer buying at the counter; only admins and cashiers can set it
+	Items         []OrderItemInput `json:"items" binding:"required,min=1,dive"`
+	RedeemPoints  int              `json:"redeem_points" binding:"omitempty,min=0"` // Loyalty points to spend as a discount; only the points needed to cover the total are used
+}
+
+type QuoteOrderInput struct {
+	Items      []OrderItemInput `json:"items" binding:"required,min=1,dive"`
+	CustomerID *uint            `json:"customer_id" binding:"omitempty,exists=customers-id"` // Prices the items from the customer's price list
+}
+
+type OrderQuoteResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    utils.OrderQuote `json:"data"`
+}
+
+func toQuoteItems(items []OrderItemInput) []utils.OrderQuoteItem {
+	quoteItems := make([]utils.OrderQuoteItem, 0, len(items))
+	for _, item := range items {
+		quoteItems = append(quoteItems, utils.OrderQuoteItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return quoteItems
+}
+
+// httpError is a failure inside a handler transaction that carries the response to send.
+type httpError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *httpError) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+// respondOrderError maps an error from utils.QuoteOrder or placeOrder to an API response.
+func respondOrderError(c *gin.Context, err error) {
+	var httpErr *httpError
+	switch {
+	case errors.As(err, &httpErr):
+		c.JSON(httpErr.Status, gin.H{"status": "error", "message": httpErr.Message, "data": httpErr.Err.Error()})
+	case errors.Is(err, utils.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Product not found", "data": err.Error()})
+	case errors.Is(err, utils.ErrPromotionProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Get product for promotion not found", "data": err.Error()})
+	case errors.Is(err, utils.ErrInsufficientPoints):
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Insufficient loyalty points", "data": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+	}
+}
+
+// storeOrderQuote creates the order items and tax breakdown of quote for order
+// and copies its totals onto order, before any loyalty points discount.
+func storeOrderQuote(tx *gorm.DB, order *models.Order, quote utils.OrderQuote) error {
+	var orderItems []models.OrderItem
+
+	for _, line := range quote.Lines {
+		orderItem := models.OrderItem{
+			OrderID:           order.ID,
+			ProductID:         line.ProductID,
+			Quantity:          line.Quantity,
+			Price:             line.Price,           // Original price per unit
+			DiscountedPrice:   line.DiscountedPrice, // Total price for all units of this item after discount
+			ItemDiscount:      line.ItemDiscount,    // Total discount for all units of this item
+			IsFreeItem:        false,
+			CartDiscountShare: line.CartDiscountShare,
+			TaxClassID:        line.TaxClassID,
+			TaxRate:           line.TaxRate,
+			TaxInclusive:      line.TaxInclusive,
+			TaxableAmount:     line.TaxableAmount,
+			TaxAmount:         line.TaxAmount,
+		}
+		// Only attribute the promotion when it actually changed the price of the line
+		if line.AppliedPromotion != nil && line.ItemDiscount > 0 {
+			orderItem.ProductPromotionID = &line.AppliedPromotion.ID
+		}
+		orderItems = append(orderItems, orderItem)
+
+		for _, freeItem := range line.FreeItems {
+			orderItems = append(orderItems, models.OrderItem{
+				OrderID:            order.ID,
+				ProductID:          freeItem.ProductID,
+				Quantity:           freeItem.Quantity,
+				Price:              freeItem.Price,
+				DiscountedPrice:    freeItem.DiscountedPrice,
+				ItemDiscount:       freeItem.ItemDiscount,
+				IsFreeItem:         true,
+				ProductPromotionID: &line.AppliedPromotion.ID,
+			})
+		}
+	}
+
+	if err := tx.Create(&orderItems).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not create order items", Err: err}
+	}
+
+	order.GrossTotal = quote.GrossTotal
+	order.ItemDiscountTotal = quote.ItemDiscountTotal
+	order.SubTotal = quote.SubTotal
+	order.CartDiscount = quote.CartDiscount
+	order.PriceListID = quote.PriceListID
+	order.CartPromotionID = nil
+	if quote.AppliedCartPromotion != nil {
+		order.CartPromotionID = &quote.AppliedCartPromotion.ID
+	}
+	order.TaxTotal = quote.TaxTotal
+	order.TotalAmount = quote.TotalAmount
+	order.OrderItems = orderItems
+
+	order.Taxes = nil
+	for _, breakdown := range quote.Taxes {
+		order.Taxes = append(order.Taxes, models.OrderTax{
+			OrderID:       order.ID,
+			TaxClassID:    breakdown.TaxClassID,
+			Name:          breakdown.Name,
+			Rate:          breakdown.Rate,
+			Inclusive:     breakdown.Inclusive,
+			TaxableAmount: breakdown.TaxableAmount,
+			TaxAmount:     breakdown.TaxAmount,
+		})
+	}
+	if len(order.Taxes) > 0 {
+		if err := tx.Create(&order.Taxes).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not create order taxes", Err: err}
+		}
+	}
+
+	return nil
+}
+
+// orderPlacedBy returns the user who placed order: the cashier for counter
+// sales, the buyer otherwise.
+func orderPlacedBy(order *models.Order) uint {
+	if order.CashierID != nil {
+		return *order.CashierID
+	}
+	if order.UserID != nil {
+		return *order.UserID
+	}
+	return 0
+}
+
+// placeOrder creates the order inside tx: it assigns the invoice number, prices
+// and taxes the items, deducts stock not held by other carts, logs the stock
+// transactions, applies redeemed loyalty points and stores the items and
+// totals on order. Payment is settled
+// separately by settleNewOrder. The caller owns the transaction.
+func placeOrder(tx *gorm.DB, order *models.Order, items []utils.OrderQuoteItem, redeemPoints int) error {
+	invoiceNumber, err := utils.NextInvoiceNumber(tx, order.CreatedAt, order.ShiftID)
+	if err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not assign invoice number", Err: err}
+	}
+	order.InvoiceNumber = &invoiceNumber
+
+	if err := tx.Create(order).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not create order", Err: err}
+	}
+
+	// Price the order with the same engine used by the quote endpoint
+	priceList, err := utils.ActivePriceList(tx, order.CustomerID)
+	if err != nil {
+		return err
+	}
+	quote, err := utils.QuoteOrder(tx, items, priceList)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range quote.Lines {
+		var product models.Product
+		// Lock the product record for update to prevent race conditions
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, line.ProductID).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Product not found", Err: err}
+		}
+
+		// Check stock availability, leaving units held by carts untouched
+		if product.Quantity-product.ReservedQuantity < line.Quantity {
+			return &httpError{Status: http.StatusBadRequest, Message: "Insufficient stock for product " + product.Name, Err: fmt.Errorf("%d available", product.Quantity-product.ReservedQuantity)}
+		}
+
+		// Decrement stock
+		product.Quantity -= line.Quantity
+		if err := tx.Save(&product).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Failed to update stock", Err: err}
+		}
+
+		// Create stock transaction log for 'out' type
+		stockTransaction := models.StockTransaction{
+			ProductID: product.ID,
+			UserID:    orderPlacedBy(order),
+			Quantity:  line.Quantity,
+			Type:      models.StockTransactionTypeOut,
+			SubType:   models.SubTypeSale,
+			Notes:     fmt.Sprintf("Sale for order %d", order.ID),
+		}
+		if err := tx.Create(&stockTransaction).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Failed to create stock transaction", Err: err}
+		}
+	}
+
+	if err := storeOrderQuote(tx, order, quote); err != nil {
+		return err
+	}
+
+	// Spend loyalty points as a discount on the amount due
+	if redeemPoints > 0 {
+		if order.UserID == nil {
+			return &httpError{Status: http.StatusBadRequest, Message: "Loyalty points can only be redeemed on orders for a user account", Err: errors.New("order has no user")}
+		}
+		points, discount := utils.PointsDiscount(redeemPoints, order.TotalAmount)
+		if err := utils.RedeemLoyaltyPoints(tx, *order.UserID, order.ID, points); err != nil {
+			return err
+		}
+		order.PointsRedeemed = points
+		order.PointsDiscount = discount
+		order.TotalAmount -= discount
+	}
+
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not update order totals", Err: err}
+	}
+
+	return nil
 }
 
 // CreateOrder handles the creation of a new order
 // @Summary Create a new order
-// @Description Create a new order with specified products and payment method.
+// @Description Create a new order with specified products. Orders are placed for the logged-in user unless an admin or cashier sets user_id; staff can also attach a store customer with customer_id, and such an order has no user unless user_id is set too. Staff can pay it with a single payment_method or with split payments that add up to the total; orders without payment stay pending until paid, and customers pay them through payment intents.
 // @Tags Orders
 // @Accept  json
 // @Produce  json
@@ -32,6 +261,8 @@ type CreateOrderInput struct {
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /orders [post]
 func CreateOrder(c *gin.Context) {
@@ -41,153 +272,290 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	tx := database.DB.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to start transaction", "data": tx.Error.Error()})
+	caller, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
 
-	order := models.Order{
-		PaymentMethod: input.PaymentMethod,
-		UserID:        input.UserID,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	if err := tx.Create(&order).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not create order", "data": err.Error()})
+	// Only staff can take manual payments; customers pay through payment intents
+	if (input.PaymentMethod != "" || len(input.Payments) > 0) && !isStaff(caller) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Only admins and cashiers can record payments"})
 		return
 	}
 
-	var orderItems []models.OrderItem
-	var grossTotal float64
-	var itemDiscountTotal float64
-
-	for _, itemInput := range input.Items {
-		var product models.Product
-		if err := tx.Preload("Promotions").First(&product, itemInput.ProductID).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Product not found", "data": err.Error()})
+	// Only staff can place orders on behalf of another user
+	orderUserID := &caller.ID
+	if input.UserID != 0 && input.UserID != caller.ID {
+		if !isStaff(caller) {
+			c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "You can only place orders for yourself"})
 			return
 		}
-
-		// Check stock availability
-		if product.Quantity < itemInput.Quantity {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Insufficient stock for product " + product.Name})
+		orderUserID = &input.UserID
+	}
+	if input.CustomerID != nil {
+		if !isStaff(caller) {
+			c.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Only admins and cashiers can attach a customer"})
 			return
 		}
+		// A sale to a store customer belongs to no account unless user_id says
+		// so; it must not count as the cashier's own purchase
+		if input.UserID == 0 {
+			orderUserID = nil
+		}
+	}
 
-		// Decrement stock
-		product.Quantity -= itemInput.Quantity
-		if err := tx.Save(&product).Error; err != nil {
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to start transaction", "data": tx.Error.Error()})
+		return
+	}
+
+	// Only staff work shifts; customer orders are not tied to one
+	var shift *models.Shift
+	if isStaff(caller) {
+		shift, err = findOpenShift(tx, caller.ID)
+		if err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update stock", "data": err.Error()})
+			respondOrderError(c, err)
 			return
 		}
-
-		// Create stock transaction log for 'out' type
-		stockTransaction := models.StockTransaction{
-			ProductID: product.ID,
-			UserID:    order.UserID,
-			Quantity:  itemInput.Quantity,
-			Type:      models.StockTransactionTypeOut,
-			SubType:   models.SubTypeSale,
-			Notes:     fmt.Sprintf("Sale for order %d", order.ID),
-		}
-		if err := tx.Create(&stockTransaction).Error; err != nil {
+		if shift == nil && requireOpenShift() {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create stock transaction", "data": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Open a shift before creating orders"})
 			return
 		}
+	}
 
-		// Calculate total price for the item, considering quantity and promotions
-		totalItemPrice, activePromotion := utils.CalculateTotalPrice(product, itemInput.Quantity)
-		originalItemTotal := product.Price * float64(itemInput.Quantity)
-		itemDiscount := originalItemTotal - totalItemPrice
-
-		orderItem := models.OrderItem{
-			OrderID:         order.ID,
-			ProductID:       product.ID,
-			Quantity:        itemInput.Quantity,
-			Price:           product.Price,  // Original price per unit
-			DiscountedPrice: totalItemPrice, // Total price for all units of this item after discount
-			ItemDiscount:    itemDiscount,   // Total discount for all units of this item
-			IsFreeItem:      false,
-		}
-		orderItems = append(orderItems, orderItem)
+	order := models.Order{
+		UserID:        orderUserID,
+		CustomerID:    input.CustomerID,
+		Status:        models.OrderStatusPending,
+		PaymentStatus: models.PaymentStatusUnpaid,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if isStaff(caller) {
+		order.CashierID = &caller.ID
+	}
+	if shift != nil {
+		order.ShiftID = &shift.ID
+	}
 
-		grossTotal += originalItemTotal
-		itemDiscountTotal += itemDiscount
-
-		// Handle "buy X get Y" promotion
-		if activePromotion != nil && activePromotion.PromotionType == "buy_x_get_y" {
-			if activePromotion.BuyProductID != nil && activePromotion.GetProductID != nil && *activePromotion.BuyProductID == product.ID {
-				// Assuming for simplicity, 1 quantity of Y for 1 quantity of X
-				var getProduct models.Product
-				if err := tx.First(&getProduct, *activePromotion.GetProductID).Error; err != nil {
-					tx.Rollback()
-					c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Get product for promotion not found", "data": err.Error()})
-					return
-				}
-				// Add the 'get Y' product as a free item (Price and DiscountedPrice are 0)
-				orderItems = append(orderItems, models.OrderItem{
-					OrderID:         order.ID,
-					ProductID:       getProduct.ID,
-					Quantity:        itemInput.Quantity, // Same quantity as the 'buy X' product
-					Price:           getProduct.Price,   // Original price of the free item
-					DiscountedPrice: 0,                  // Free item
-					ItemDiscount:    getProduct.Price,   // Discount is the full price of the item
-					IsFreeItem:      true,
-				})
-			}
-		}
+	if err := placeOrder(tx, &order, toQuoteItems(input.Items), input.RedeemPoints); err != nil {
+		tx.Rollback()
+		respondOrderError(c, err)
+		return
 	}
 
-	if err := tx.Create(&orderItems).Error; err != nil {
+	if err := settleNewOrder(tx, &order, input.PaymentMethod, input.Payments, caller.ID); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not create order items", "data": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
-	order.GrossTotal = grossTotal
-	order.ItemDiscountTotal = itemDiscountTotal
-	order.SubTotal = grossTotal - itemDiscountTotal
+	tx.Commit()
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Order created", "data": order})
+}
 
-	// Calculate cart-level discount
-	order.CartDiscount = utils.CalculateCartDiscount(order.SubTotal)
+// QuoteOrder handles pricing an order without placing it
+// @Summary Get a price quote for an order
+// @Description Price the given items with the active product and cart promotions, exactly as checkout would, without reserving or deducting stock.
+// @Tags Orders
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   quote   body    QuoteOrderInput true "Items to price"
+// @Success 200 {object} OrderQuoteResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/quote [post]
+func QuoteOrder(c *gin.Context) {
+	var input QuoteOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
 
-	order.TotalAmount = order.SubTotal - order.CartDiscount
+	priceList, err := utils.ActivePriceList(database.DB, input.CustomerID)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
 
-	if err := tx.Save(&order).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not update order totals", "data": err.Error()})
+	quote, err := utils.QuoteOrder(database.DB, toQuoteItems(input.Items), priceList)
+	if err != nil {
+		respondOrderError(c, err)
 		return
 	}
 
-	tx.Commit()
-	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Order created", "data": order})
+	c.JSON(http.StatusOK, OrderQuoteResponse{Status: "success", Message: "Order quoted", Data: quote})
 }
 
-// GetOrders handles fetching all orders
+// orderSortColumns maps the sort query values of GetOrders to columns.
+var orderSortColumns = map[string]string{
+	"id":           "orders.id",
+	"created_at":   "orders.created_at",
+	"total_amount": "orders.total_amount",
+}
+
+// filterOrders applies the filter query parameters of GetOrders to query.
+func filterOrders(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if value := c.Query("from"); value != "" {
+		from, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date: %w", err)
+		}
+		query = query.Where("orders.created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date: %w", err)
+		}
+		query = query.Where("orders.created_at < ?", to.AddDate(0, 0, 1))
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := utils.GetInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user_id: %w", err)
+		}
+		query = query.Where("orders.user_id = ?", userID)
+	}
+	if value := c.Query("invoice_number"); value != "" {
+		query = query.Where("orders.invoice_number ILIKE ?", "%"+value+"%")
+	}
+	if value := c.Query("customer_id"); value != "" {
+		customerID, err := utils.GetInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid customer_id: %w", err)
+		}
+		query = query.Where("orders.customer_id = ?", customerID)
+	}
+	if value := c.Query("payment_method"); value != "" {
+		query = query.Where("orders.payment_method = ?", value)
+	}
+	if value := c.Query("status"); value != "" {
+		query = query.Where("orders.status = ?", value)
+	}
+	if value := c.Query("payment_status"); value != "" {
+		query = query.Where("orders.payment_status = ?", value)
+	}
+	if value := c.Query("min_total"); value != "" {
+		minTotal, err := models.ParseMoney(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_total: %w", err)
+		}
+		query = query.Where("orders.total_amount >= ?", minTotal)
+	}
+	if value := c.Query("max_total"); value != "" {
+		maxTotal, err := models.ParseMoney(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_total: %w", err)
+		}
+		query = query.Where("orders.total_amount <= ?", maxTotal)
+	}
+	if value := c.Query("product_id"); value != "" {
+		productID, err := utils.GetInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product_id: %w", err)
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.product_id = ?)", productID)
+	}
+	return query, nil
+}
+
+// GetOrders handles fetching orders
 // @Summary Get all orders
-// @Description Get a list of all orders. Admin can see all, users see their own.
+// @Description Get a paginated list of orders. Admin can see all, cashiers see the orders they placed and their own, users see their own. Summary mode leaves out the order items.
 // @Tags Orders
 // @Produce  json
 // @Security BearerAuth
+// @Param   page           query    int     false  "Page number"
+// @Param   limit          query    int     false  "Number of orders per page"
+// @Param   from           query    string  false  "Created on or after this date (YYYY-MM-DD)"
+// @Param   to             query    string  false  "Created on or before this date (YYYY-MM-DD)"
+// @Param   invoice_number query    string  false  "Part of the invoice number"
+// @Param   user_id        query    int     false  "User the order is for"
+// @Param   customer_id    query    int     false  "Store customer"
+// @Param   payment_method query    string  false  "Payment method, or split"
+// @Param   status         query    string  false  "Order status"
+// @Param   payment_status query    string  false  "Payment status"
+// @Param   min_total      query    string  false  "Minimum total amount"
+// @Param   max_total      query    string  false  "Maximum total amount"
+// @Param   product_id     query    int     false  "Only orders containing this product"
+// @Param   sort           query    string  false  "Sort by id, created_at (default) or total_amount"
+// @Param   order          query    string  false  "Sort direction: asc or desc (default)"
+// @Param   summary        query    bool    false  "Leave out the order items"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /orders [get]
 func GetOrders(c *gin.Context) {
-	var orders []models.Order
-	database.DB.Preload("OrderItems.Product").Find(&orders)
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Orders fetched", "data": orders})
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	page, _ := utils.GetInt(c.DefaultQuery("page", "1"))
+	limit, _ := utils.GetInt(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	column, ok := orderSortColumns[c.DefaultQuery("sort", "created_at")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "sort must be one of id, created_at or total_amount"})
+		return
+	}
+	direction := c.DefaultQuery("order", "desc")
+	if direction != "asc" && direction != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "order must be asc or desc"})
+		return
+	}
+
+	query, err := filterOrders(c, scopeOrders(database.DB.Model(&models.Order{}), user))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+		return
+	}
+
+	if c.Query("summary") != "true" {
+		query = query.Preload("OrderItems.Product")
+	}
+	orders := []models.Order{}
+	if err := query.Order(column + " " + direction).Order("orders.id " + direction).
+		Limit(limit).Offset(offset).Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Orders fetched",
+		"data":    orders,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
 }
 
 // GetOrderByID handles fetching a single order by ID
 // @Summary Get an order by ID
-// @Description Get a single order by its ID. Admin can see any order, users see their own.
+// @Description Get a single order by its ID. Admin can see any order, cashiers the orders they placed and their own, users their own.
 // @Tags Orders
 // @Produce  json
 // @Security BearerAuth
@@ -198,10 +566,123 @@ func GetOrders(c *gin.Context) {
 // @Router /orders/{id} [get]
 func GetOrderByID(c *gin.Context) {
 	id := c.Param("id")
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
 	var order models.Order
-	if err := database.DB.Preload("OrderItems.Product").First(&order, id).Error; err != nil {
+	if err := scopeOrders(database.DB, user).Preload("OrderItems.Product").Preload("Taxes").Preload("Payments").Preload("History").Preload("Customer").First(&order, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Order not found", "data": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Order fetched", "data": order})
 }
+
+// closeOrder moves an order to the given status, puts the sold stock back and
+// reverses the loyalty points of the order.
+func closeOrder(c *gin.Context, status string, notes string) {
+	id := c.Param("id")
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var order models.Order
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").First(&order, id).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Order not found", Err: err}
+		}
+		// Pending orders can be cancelled, but only completed orders can be refunded
+		if order.Status != models.OrderStatusCompleted && (order.Status != models.OrderStatusPending || status != models.OrderStatusCancelled) {
+			return &httpError{Status: http.StatusBadRequest, Message: "Order cannot be " + status, Err: fmt.Errorf("order is %s", order.Status)}
+		}
+
+		for _, item := range order.OrderItems {
+			// Free items were never deducted from stock, so there is nothing to put back
+			if item.IsFreeItem {
+				continue
+			}
+			if err := tx.Model(&models.Product{}).Where("id = ?", item.ProductID).
+				Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error; err != nil {
+				return &httpError{Status: http.StatusInternalServerError, Message: "Failed to update stock", Err: err}
+			}
+			stockTransaction := models.StockTransaction{
+				ProductID: item.ProductID,
+				UserID:    userID,
+				Quantity:  item.Quantity,
+				Type:      models.StockTransactionTypeIn,
+				SubType:   models.SubTypeReturn,
+				Notes:     fmt.Sprintf("%s for order %d", notes, order.ID),
+			}
+			if err := tx.Create(&stockTransaction).Error; err != nil {
+				return &httpError{Status: http.StatusInternalServerError, Message: "Failed to create stock transaction", Err: err}
+			}
+		}
+
+		order.Status = status
+		if err := utils.ReverseOrderLoyalty(tx, order); err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not reverse loyalty points", Err: err}
+		}
+
+		if err := recordCashRefund(tx, order, userID); err != nil {
+			return err
+		}
+		if err := markOrderIntentsForRefund(tx, order); err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not schedule payment refunds", Err: err}
+		}
+
+		updates := map[string]any{"status": status}
+		// Whatever was paid on the order is handed back
+		if order.PaymentStatus != models.PaymentStatusUnpaid {
+			order.PaymentStatus = models.PaymentStatusRefunded
+			updates["payment_status"] = order.PaymentStatus
+		}
+		return tx.Model(&order).Updates(updates).Error
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	// Gateway payments are refunded only now that the order change is committed
+	message := "Order " + status
+	if err := refundPendingIntents(c.Request.Context(), order.ID); err != nil {
+		message += "; the card or e-wallet refund failed and will be retried"
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": message, "data": order})
+}
+
+// CancelOrder handles cancelling an order
+// @Summary Cancel an order
+// @Description Cancel a pending or completed order. Stock is returned, loyalty points earned or redeemed on the order are reversed and cash paid is recorded as taken out of a shift drawer. Admin only.
+// @Tags Orders
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /orders/{id}/cancel [post]
+func CancelOrder(c *gin.Context) {
+	closeOrder(c, models.OrderStatusCancelled, "Cancellation")
+}
+
+// RefundOrder handles refunding an order
+// @Summary Refund an order
+// @Description Refund a completed order. Stock is returned, loyalty points earned or redeemed on the order are reversed and cash paid is recorded as taken out of a shift drawer. Admin only.
+// @Tags Orders
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /orders/{id}/refund [post]
+func RefundOrder(c *gin.Context) {
+	closeOrder(c, models.OrderStatusRefunded, "Refund")
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"pos/database"
+	"pos/gateway"
+	"pos/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CreatePaymentIntentInput struct {
+	Method string        `json:"method" binding:"required,oneof=card e_wallet"`
+	Amount *models.Money `json:"amount" binding:"omitempty,gt=0"` // Defaults to the outstanding balance
+}
+
+// gatewayError maps a payment provider failure to a response.
+func gatewayError(message string, err error) *httpError {
+	switch {
+	case errors.Is(err, gateway.ErrIntentNotFound):
+		return &httpError{Status: http.StatusNotFound, Message: message, Err: err}
+	case errors.Is(err, gateway.ErrInvalidIntent):
+		return &httpError{Status: http.StatusBadRequest, Message: message, Err: err}
+	case errors.Is(err, gateway.ErrNoProvider), errors.Is(err, gateway.ErrUnknownProvider):
+		return &httpError{Status: http.StatusServiceUnavailable, Message: message, Err: err}
+	default:
+		return &httpError{Status: http.StatusBadGateway, Message: message, Err: err}
+	}
+}
+
+// recordIntentPayment marks a captured intent as succeeded and records its
+// payment on the order. It does nothing when the intent already succeeded, so
+// captures and webhooks for the same intent can arrive in any order. A capture
+// the order can no longer take is left for refund instead of being rejected,
+// as the provider already holds the money.
+func recordIntentPayment(tx *gorm.DB, intentID uint) error {
+	var intent models.PaymentIntent
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&intent, intentID).Error; err != nil {
+		return &httpError{Status: http.StatusNotFound, Message: "Payment intent not found", Err: err}
+	}
+	if intent.Status != models.PaymentIntentRequiresCapture {
+		return nil
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, intent.OrderID).Error; err != nil {
+		return &httpError{Status: http.StatusNotFound, Message: "Order not found", Err: err}
+	}
+	if order.Status != models.OrderStatusPending {
+		// The order was closed while the customer was paying, so the captured money goes back
+		log.Printf("payment intent %d captured for %s order %d, refunding it", intent.ID, order.Status, order.ID)
+		intent.Status = models.PaymentIntentRefundPending
+		return tx.Save(&intent).Error
+	}
+
+	// Stop setting this intent's amount aside before working out the balance
+	intent.Status = models.PaymentIntentSucceeded
+	if err := tx.Save(&intent).Error; err != nil {
+		return err
+	}
+	_, balance, err := orderBalance(tx, &order)
+	if err != nil {
+		return err
+	}
+	if intent.Amount > balance {
+		// The balance was paid some other way while the customer was paying
+		log.Printf("payment intent %d captured %s but order %d has %s left to pay, refunding it", intent.ID, intent.Amount, order.ID, balance)
+		intent.Status = models.PaymentIntentRefundPending
+		return tx.Save(&intent).Error
+	}
+
+	payments := []models.Payment{{
+		OrderID:   order.ID,
+		Method:    intent.Method,
+		Amount:    intent.Amount,
+		Tendered:  intent.Amount,
+		Reference: intent.ProviderIntentID,
+		UserID:    intent.UserID,
+	}}
+	if err := applyOrderPayments(tx, &order, payments); err != nil {
+		return err
+	}
+
+	intent.PaymentID = &order.Payments[len(order.Payments)-1].ID
+	return tx.Save(&intent).Error
+}
+
+// refundAttemptTimeout is how long a refund sent to the provider is left alone
+// before another attempt may claim it.
+const refundAttemptTimeout = 5 * time.Minute
+
+// markOrderIntentsForRefund flags the captured gateway payments of a closed
+// order for refund. The provider is only called by refundPendingIntents once
+// the order change has been committed.
+func markOrderIntentsForRefund(tx *gorm.DB, order models.Order) error {
+	return tx.Model(&models.PaymentIntent{}).
+		Where("order_id = ? AND status = ?", order.ID, models.PaymentIntentSucceeded).
+		Update("status", models.PaymentIntentRefundPending).Error
+}
+
+// refundPendingIntents refunds through their provider whatever was captured on
+// intents waiting for a refund, for one order or for all orders when orderID
+// is 0. Intents whose refund fails stay pending and are retried later.
+func refundPendingIntents(ctx context.Context, orderID uint) error {
+	query := database.DB.Where("status = ?", models.PaymentIntentRefundPending)
+	if orderID != 0 {
+		query = query.Where("order_id = ?", orderID)
+	}
+	var intents []models.PaymentIntent
+	if err := query.Order("id").Find(&intents).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, intent := range intents {
+		if err := refundIntent(ctx, intent); err != nil {
+			log.Printf("refund of payment intent %d failed: %v", intent.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// refundIntent claims a pending intent, refunds it with its provider and marks
+// it refunded. The claim keeps two attempts from refunding the same intent.
+func refundIntent(ctx context.Context, intent models.PaymentIntent) error {
+	now := time.Now()
+	claim := database.DB.Model(&models.PaymentIntent{}).
+		Where("id = ? AND status = ? AND (refund_attempt_at IS NULL OR refund_attempt_at < ?)",
+			intent.ID, models.PaymentIntentRefundPending, now.Add(-refundAttemptTimeout)).
+		Update("refund_attempt_at", now)
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return claim.Error
+	}
+
+	provider, err := gateway.Get(intent.Provider)
+	if err != nil {
+		return err
+	}
+	// Reload the intent, a refund webhook may have arrived in the meantime
+	if err := database.DB.First(&intent, intent.ID).Error; err != nil {
+		return err
+	}
+	if amount := intent.Amount - intent.RefundedAmount; amount > 0 {
+		if _, err := provider.Refund(ctx, intent.ProviderIntentID, amount); err != nil {
+			// Let the next attempt retry straight away
+			database.DB.Model(&intent).Update("refund_attempt_at", nil)
+			return err
+		}
+	}
+	return database.DB.Model(&intent).Updates(map[string]any{
+		"refunded_amount": intent.Amount,
+		"status":          models.PaymentIntentRefunded,
+	}).Error
+}
+
+// CreatePaymentIntent handles starting a gateway payment for an order
+// @Summary Start a card or e-wallet payment
+// @Description Create a payment intent with the configured payment provider for a pending order. The amount defaults to the balance not yet paid or being paid. The returned client secret is handed to the card terminal or wallet app.
+// @Tags Payments
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Param   intent  body    CreatePaymentIntentInput true "Payment details"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /orders/{id}/payment-intents [post]
+func CreatePaymentIntent(c *gin.Context) {
+	id := c.Param("id")
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input CreatePaymentIntentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	provider, err := gateway.Default()
+	if err != nil {
+		respondOrderError(c, gatewayError("Payment provider not available", err))
+		return
+	}
+
+	var order models.Order
+	if err := scopeOrders(database.DB, user).First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Order not found", "data": err.Error()})
+		return
+	}
+	if order.Status != models.OrderStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Only pending orders can take payments", "data": "order is " + order.Status})
+		return
+	}
+
+	_, balance, err := orderBalance(database.DB, &order)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	amount := balance
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+	if amount <= 0 || amount > balance {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": fmt.Sprintf("Amount must be between 0 and the outstanding balance of %s", balance)})
+		return
+	}
+
+	result, err := provider.CreateIntent(c.Request.Context(), gateway.IntentRequest{OrderID: order.ID, Method: input.Method, Amount: amount})
+	if err != nil {
+		respondOrderError(c, gatewayError("Could not create payment intent", err))
+		return
+	}
+
+	intent := models.PaymentIntent{
+		OrderID:          order.ID,
+		Provider:         provider.Name(),
+		ProviderIntentID: result.ID,
+		Method:           input.Method,
+		Amount:           result.Amount,
+		Status:           result.Status,
+		UserID:           user.ID,
+	}
+	if err := database.DB.Create(&intent).Error; err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	intent.ClientSecret = result.ClientSecret
+
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Payment intent created", "data": intent})
+}
+
+// CapturePaymentIntent handles capturing an authorized gateway payment
+// @Summary Capture a card or e-wallet payment
+// @Description Capture an authorized payment intent with its provider and record the payment on the order. Capturing an intent that already succeeded has no further effect.
+// @Tags Payments
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Payment intent ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /payment-intents/{id}/capture [post]
+func CapturePaymentIntent(c *gin.Context) {
+	id := c.Param("id")
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var intent models.PaymentIntent
+	if err := database.DB.First(&intent, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Payment intent not found", "data": err.Error()})
+		return
+	}
+	if err := scopeOrders(database.DB, user).First(&models.Order{}, intent.OrderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Payment intent not found", "data": err.Error()})
+		return
+	}
+
+	provider, err := gateway.Get(intent.Provider)
+	if err != nil {
+		respondOrderError(c, gatewayError("Payment provider not available", err))
+		return
+	}
+
+	result, err := provider.Capture(c.Request.Context(), intent.ProviderIntentID)
+	if err != nil {
+		respondOrderError(c, gatewayError("Could not capture payment", err))
+		return
+	}
+
+	if result.Status == models.PaymentIntentSucceeded {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return recordIntentPayment(tx, intent.ID)
+		}); err != nil {
+			respondOrderError(c, err)
+			return
+		}
+	}
+
+	if err := database.DB.First(&intent, intent.ID).Error; err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Payment intent " + intent.Status, "data": intent})
+}
+
+// HandlePaymentWebhook handles notifications from a payment provider
+// @Summary Receive a payment provider webhook
+// @Description Verify the signature of a provider event and apply it: succeeded payments are recorded on their order, failed payments and refunds update the payment intent. Events are applied once; redelivered events are acknowledged without effect.
+// @Tags Payments
+// @Accept  json
+// @Produce  json
+// @Param   provider path   string  true        "Payment provider name"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /payments/webhooks/{provider} [post]
+func HandlePaymentWebhook(c *gin.Context) {
+	provider, err := gateway.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Could not read request body"})
+		return
+	}
+
+	event, err := provider.ParseWebhook(payload, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: "Invalid signature"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	duplicate := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		record := models.WebhookEvent{Provider: provider.Name(), EventID: event.ID, Type: event.Type, Payload: string(payload)}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		// Events that are not about a payment intent are acknowledged and ignored
+		if event.IntentID == "" {
+			return nil
+		}
+		var intent models.PaymentIntent
+		if err := tx.Where("provider = ? AND provider_intent_id = ?", provider.Name(), event.IntentID).First(&intent).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Payment intent not found", Err: err}
+		}
+
+		switch event.Type {
+		case gateway.EventPaymentSucceeded:
+			return recordIntentPayment(tx, intent.ID)
+		case gateway.EventPaymentFailed:
+			return tx.Model(&intent).Where("status = ?", models.PaymentIntentRequiresCapture).Update("status", models.PaymentIntentFailed).Error
+		case gateway.EventRefundSucceeded:
+			updates := map[string]any{"refunded_amount": event.AmountRefunded}
+			if event.AmountRefunded >= intent.Amount {
+				updates["status"] = models.PaymentIntentRefunded
+			}
+			return tx.Model(&intent).Where("refunded_amount < ?", event.AmountRefunded).Updates(updates).Error
+		}
+		return nil
+	})
+	if err != nil {
+		var httpErr *httpError
+		if errors.As(err, &httpErr) {
+			c.JSON(httpErr.Status, models.MessageResponse{Message: httpErr.Message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not process event"})
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, models.MessageResponse{Message: "Event already processed"})
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Event processed"})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentInput struct {
+	Method    string        `json:"method" binding:"required,oneof=cash card voucher e_wallet"`
+	Amount    models.Money  `json:"amount" binding:"required,gt=0"`    // Amount applied to the order
+	Tendered  *models.Money `json:"tendered" binding:"omitempty,gt=0"` // Cash handed over; defaults to the amount
+	Reference string        `json:"reference"`                         // Required for vouchers
+}
+
+type AddOrderPaymentsInput struct {
+	Payments []PaymentInput `json:"payments" binding:"required,min=1,dive"`
+}
+
+// buildPayments turns the payment inputs into payments for the order, working
+// out the change on cash payments.
+func buildPayments(order *models.Order, inputs []PaymentInput, userID uint) ([]models.Payment, models.Money, error) {
+	var payments []models.Payment
+	var total models.Money
+
+	for _, input := range inputs {
+		payment := models.Payment{
+			OrderID:   order.ID,
+			Method:    input.Method,
+			Amount:    input.Amount,
+			Tendered:  input.Amount,
+			Reference: input.Reference,
+			UserID:    userID,
+		}
+
+		switch input.Method {
+		case models.PaymentMethodCash:
+			if input.Tendered != nil {
+				if *input.Tendered < input.Amount {
+					return nil, 0, &httpError{Status: http.StatusBadRequest, Message: "Tendered cash is less than the payment amount", Err: fmt.Errorf("tendered %s for %s", input.Tendered, input.Amount)}
+				}
+				payment.Tendered = *input.Tendered
+				payment.Change = payment.Tendered - payment.Amount
+			}
+		case models.PaymentMethodVoucher:
+			if input.Reference == "" {
+				return nil, 0, &httpError{Status: http.StatusBadRequest, Message: "Voucher payments require a reference", Err: errors.New("missing voucher reference")}
+			}
+		}
+
+		payments = append(payments, payment)
+		total += payment.Amount
+	}
+
+	return payments, total, nil
+}
+
+// paymentMethodSummary returns the single method used to pay an order, or "split" when several were used.
+func paymentMethodSummary(payments []models.Payment) string {
+	method := ""
+	for _, payment := range payments {
+		if method != "" && method != payment.Method {
+			return "split"
+		}
+		method = payment.Method
+	}
+	return method
+}
+
+// completeOrder marks a fully paid order as completed and credits the loyalty
+// points earned on it to the order's user, if it has one.
+func completeOrder(tx *gorm.DB, order *models.Order) error {
+	if order.UserID != nil {
+		earned, err := utils.AwardLoyaltyPoints(tx, *order.UserID, order.ID, order.TotalAmount)
+		if err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not award loyalty points", Err: err}
+		}
+		order.PointsEarned = earned
+	}
+
+	order.Status = models.OrderStatusCompleted
+	order.PaymentStatus = models.PaymentStatusPaid
+	return nil
+}
+
+// settleNewOrder records the payments of a freshly placed order. The payments
+// must add up to the order total; a payment method without payments pays the
+// whole order with that method. Orders without payments stay pending.
+func settleNewOrder(tx *gorm.DB, order *models.Order, paymentMethod string, inputs []PaymentInput, userID uint) error {
+	if len(inputs) == 0 && paymentMethod != "" && order.TotalAmount > 0 {
+		inputs = []PaymentInput{{Method: paymentMethod, Amount: order.TotalAmount}}
+	}
+
+	payments, paid, err := buildPayments(order, inputs, userID)
+	if err != nil {
+		return err
+	}
+	if len(payments) > 0 && paid != order.TotalAmount {
+		return &httpError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Payments must add up to the order total of %s", order.TotalAmount), Err: fmt.Errorf("payments total %s", paid)}
+	}
+
+	if len(payments) > 0 {
+		if err := assignPaymentShifts(tx, payments); err != nil {
+			return err
+		}
+		if err := tx.Create(&payments).Error; err != nil {
+			return &httpError{Status: http.StatusInternalServerError, Message: "Could not record payments", Err: err}
+		}
+		order.PaymentMethod = paymentMethodSummary(payments)
+	} else {
+		order.PaymentMethod = paymentMethod
+	}
+	order.Payments = payments
+
+	if paid == order.TotalAmount {
+		if err := completeOrder(tx, order); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not update order payment", Err: err}
+	}
+	return nil
+}
+
+// orderBalance returns what has been paid on an order and the balance left to
+// pay. Gateway payments authorized but not yet captured are set aside, so the
+// balance never covers money that is already being collected.
+func orderBalance(tx *gorm.DB, order *models.Order) (paid, balance models.Money, err error) {
+	if err := tx.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("order_id = ?", order.ID).
+		Scan(&paid).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var inProgress models.Money
+	if err := tx.Model(&models.PaymentIntent{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("order_id = ? AND status = ?", order.ID, models.PaymentIntentRequiresCapture).
+		Scan(&inProgress).Error; err != nil {
+		return 0, 0, err
+	}
+
+	return paid, order.TotalAmount - paid - inProgress, nil
+}
+
+// applyOrderPayments records payments on a pending order that is locked in tx.
+// The payments may not exceed the outstanding balance; once the order is fully
+// paid it is completed.
+func applyOrderPayments(tx *gorm.DB, order *models.Order, payments []models.Payment) error {
+	alreadyPaid, balance, err := orderBalance(tx, order)
+	if err != nil {
+		return err
+	}
+
+	var paid models.Money
+	for _, payment := range payments {
+		paid += payment.Amount
+	}
+	if paid > balance {
+		return &httpError{Status: http.StatusBadRequest, Message: fmt.Sprintf("Payments exceed the outstanding balance of %s", balance), Err: fmt.Errorf("payments total %s", paid)}
+	}
+	if err := assignPaymentShifts(tx, payments); err != nil {
+		return err
+	}
+	if err := tx.Create(&payments).Error; err != nil {
+		return &httpError{Status: http.StatusInternalServerError, Message: "Could not record payments", Err: err}
+	}
+
+	if err := tx.Where("order_id = ?", order.ID).Order("id").Find(&order.Payments).Error; err != nil {
+		return err
+	}
+	order.PaymentMethod = paymentMethodSummary(order.Payments)
+	order.PaymentStatus = models.PaymentStatusPartiallyPaid
+	if alreadyPaid+paid == order.TotalAmount {
+		if err := completeOrder(tx, order); err != nil {
+			return err
+		}
+	}
+
+	return tx.Omit(clause.Associations).Save(order).Error
+}
+
+// AddOrderPayments handles paying a pending order
+// @Summary Add payments to an order
+// @Description Add one or more payments to a pending order (admin or cashier only). Payments may not exceed the outstanding balance, which leaves out card and e-wallet payments waiting to be captured; once the order is fully paid it is completed and loyalty points are awarded.
+// @Tags Orders
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Param   payments body   AddOrderPaymentsInput true "Payments"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /orders/{id}/payments [post]
+func AddOrderPayments(c *gin.Context) {
+	id := c.Param("id")
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input AddOrderPaymentsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	var order models.Order
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := scopeOrders(tx, user).Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "Order not found", Err: err}
+		}
+		if order.Status != models.OrderStatusPending {
+			return &httpError{Status: http.StatusBadRequest, Message: "Only pending orders can take payments", Err: fmt.Errorf("order is %s", order.Status)}
+		}
+
+		payments, _, err := buildPayments(&order, input.Payments, user.ID)
+		if err != nil {
+			return err
+		}
+		return applyOrderPayments(tx, &order, payments)
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Payment recorded", "data": order})
+}
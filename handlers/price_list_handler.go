@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"pos/database"
+	"pos/models"
+
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PriceListItemInput struct {
+	ProductID       uint          `json:"product_id" binding:"required,exists=products-id"`
+	MinQuantity     int           `json:"min_quantity" binding:"omitempty,min=1"`               // Quantity the price starts at, default 1
+	Price           *models.Money `json:"price" binding:"omitempty,gte=0"`                      // Fixed unit price; either this or discount_percent is required
+	DiscountPercent *models.Money `json:"discount_percent" binding:"omitempty,gte=0,lte=10000"` // Percent off the list price, 0.00 - 100.00
+}
+
+type PriceListInput struct {
+	Name            string               `json:"name" binding:"required"`
+	Description     string               `json:"description"`
+	DiscountPercent models.Money         `json:"discount_percent" binding:"gte=0,lte=10000"` // Percent off products not on the list, 0.00 - 100.00
+	IsDefault       bool                 `json:"is_default"`
+	Active          *bool                `json:"active"` // Defaults to true
+	Items           []PriceListItemInput `json:"items" binding:"omitempty,dive"`
+}
+
+type PriceListsResponse struct {
+	Data []models.PriceList `json:"data"`
+}
+
+type PriceListResponse struct {
+	Data models.PriceList `json:"data"`
+}
+
+// applyPriceListInput copies data onto priceList and replaces its items.
+func applyPriceListInput(priceList *models.PriceList, data PriceListInput) error {
+	priceList.Name = data.Name
+	priceList.Description = data.Description
+	priceList.DiscountPercent = data.DiscountPercent
+	priceList.IsDefault = data.IsDefault
+	priceList.Active = data.Active == nil || *data.Active
+
+	priceList.Items = make([]models.PriceListItem, 0, len(data.Items))
+	for i, item := range data.Items {
+		if item.Price == nil && item.DiscountPercent == nil {
+			return fmt.Errorf("items[%d]: price or discount_percent is required", i)
+		}
+		minQuantity := item.MinQuantity
+		if minQuantity == 0 {
+			minQuantity = 1
+		}
+		priceList.Items = append(priceList.Items, models.PriceListItem{
+			ProductID:       item.ProductID,
+			MinQuantity:     minQuantity,
+			Price:           item.Price,
+			DiscountPercent: item.DiscountPercent,
+		})
+	}
+	return nil
+}
+
+// savePriceList stores priceList with its items, keeping at most one default list.
+func savePriceList(priceList *models.PriceList) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if priceList.IsDefault {
+			if err := tx.Model(&models.PriceList{}).Where("id <> ? AND is_default = ?", priceList.ID, true).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+
+		items := priceList.Items
+		priceList.Items = nil
+		if err := tx.Save(priceList).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("price_list_id = ?", priceList.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].PriceListID = priceList.ID
+		}
+		if len(items) > 0 {
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+		}
+		priceList.Items = items
+		return nil
+	})
+}
+
+// @Summary Get all price lists
+// @Description Get a list of all price lists with their items. Admin only.
+// @Tags Price Lists
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} PriceListsResponse
+// @Router /price-lists [get]
+func GetPriceLists(c *gin.Context) {
+	priceLists := []models.PriceList{}
+	database.DB.Preload("Items").Order("name").Find(&priceLists)
+	c.JSON(http.StatusOK, PriceListsResponse{Data: priceLists})
+}
+
+// @Summary Create a new price list
+// @Description Create a price list such as retail, wholesale or member prices. Items set a fixed unit price or a percentage off the list price from a minimum quantity; several items for one product give quantity breaks. Admin only.
+// @Tags Price Lists
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   price_list body    PriceListInput true "Price list data"
+// @Success 201 {object} PriceListResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /price-lists [post]
+func StorePriceList(c *gin.Context) {
+	var data PriceListInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	isDup, err := utils.IsDuplicate[models.PriceList](database.DB, "name", data.Name, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Price list already exists"})
+		return
+	}
+
+	var priceList models.PriceList
+	if err := applyPriceListInput(&priceList, data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+	if err := savePriceList(&priceList); err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not create price list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, PriceListResponse{Data: priceList})
+}
+
+// @Summary Get a price list by ID
+// @Description Get a single price list with its items by its ID. Admin only.
+// @Tags Price Lists
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Price list ID"
+// @Success 200 {object} PriceListResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /price-lists/{id} [get]
+func GetPriceListByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var priceList models.PriceList
+	database.DB.Preload("Items").First(&priceList, id)
+
+	if priceList.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Price list not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PriceListResponse{Data: priceList})
+}
+
+// @Summary Update a price list by ID
+// @Description Update a price list by its ID, replacing its items. Orders already placed keep their prices. Admin only.
+// @Tags Price Lists
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Price list ID"
+// @Param   price_list body    PriceListInput true "Price list data to update"
+// @Success 200 {object} PriceListResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /price-lists/{id} [put]
+func UpdatePriceListByID(c *gin.Context) {
+	id := c.Param("id")
+	var data PriceListInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var priceList models.PriceList
+	database.DB.First(&priceList, id)
+
+	if priceList.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Price list not found"})
+		return
+	}
+
+	isDup, err := utils.IsDuplicate[models.PriceList](database.DB, "name", data.Name, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Price list name already exists"})
+		return
+	}
+
+	if err := applyPriceListInput(&priceList, data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+	if err := savePriceList(&priceList); err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not update price list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PriceListResponse{Data: priceList})
+}
+
+// @Summary Delete a price list by ID
+// @Description Delete a price list by its ID. Customers on it go back to the default price list. Admin only.
+// @Tags Price Lists
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Price list ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /price-lists/{id} [delete]
+func DeletePriceListByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var priceList models.PriceList
+	database.DB.First(&priceList, id)
+
+	if priceList.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Price list not found"})
+		return
+	}
+
+	database.DB.Model(&models.Customer{}).Where("price_list_id = ?", priceList.ID).Update("price_list_id", nil)
+	database.DB.Delete(&priceList, id)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Price list deleted"})
+}
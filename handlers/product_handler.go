@@ -16,10 +16,11 @@ import (
 )
 
 type ProductInput struct {
-	Name       string  `json:"name" binding:"required"`
-	Price      float64 `json:"price" binding:"required"`
-	SKU        string  `json:"sku" binding:"required"`
-	CategoryID *uint   `json:"category_id"`
+	Name       string       `json:"name" binding:"required"`
+	Price      models.Money `json:"price" binding:"required"`
+	SKU        string       `json:"sku" binding:"required"`
+	CategoryID *uint        `json:"category_id"`
+	TaxClassID *uint        `json:"tax_class_id" binding:"omitempty,exists=tax_classes-id"`
 }
 
 type ProductResponse struct {
@@ -27,24 +28,52 @@ type ProductResponse struct {
 	CategoryName     string                   `json:"category_name"`
 	Quantity         int                      `json:"quantity"`
 	ReservedQuantity int                      `json:"reserved_quantity"`
-	DiscountedPrice  float64                  `json:"discounted_price"`
+	UnitPrice        models.Money             `json:"unit_price"` // Price on the active price list, before promotions
+	DiscountedPrice  models.Money             `json:"discounted_price"`
 	ActivePromotion  *models.ProductPromotion `json:"active_promotion,omitempty"`
+	PriceListID      *uint                    `json:"price_list_id,omitempty"`
+}
+
+// productPriceList returns the price list named by the price_list_id query
+// parameter, or the default price list when there is none.
+func productPriceList(c *gin.Context) (*models.PriceList, error) {
+	value := c.Query("price_list_id")
+	if value == "" {
+		return utils.ActivePriceList(database.DB, nil)
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price_list_id: %w", err)
+	}
+	priceList, err := utils.LoadPriceList(database.DB, uint(id))
+	if err == nil && priceList == nil {
+		err = fmt.Errorf("price list %d not found", id)
+	}
+	return priceList, err
 }
 
 // @Summary Get all products
-// @Description Get a list of all products with pagination.
+// @Description Get a list of all products with pagination, priced from the given or the default price list.
 // @Tags Products
 // @Produce  json
 // @Security BearerAuth
 // @Param   page      query    int     false        "Page number"
 // @Param   limit     query    int     false        "Number of items per page"
+// @Param   price_list_id query int    false        "Price list to price the products from"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /products [get]
 func GetAllProducts(c *gin.Context) {
 	var products []models.Product
 	var total int64
 
+	priceList, err := productPriceList(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
 	page, _ := utils.GetInt(c.DefaultQuery("page", "1"))
 	limit, _ := utils.GetInt(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
@@ -56,14 +85,19 @@ func GetAllProducts(c *gin.Context) {
 
 	var productResponses []ProductResponse
 	for _, p := range products {
-		discountedPrice, activePromotion := utils.CalculateTotalPrice(p, 1)
-		productResponses = append(productResponses, ProductResponse{
+		discountedPrice, activePromotion := utils.CalculateTotalPrice(p, 1, priceList)
+		response := ProductResponse{
 			Product:         p,
 			CategoryName:    p.Category.Name,
 			Quantity:        p.Quantity,
+			UnitPrice:       utils.PriceListUnitPrice(priceList, p, 1),
 			DiscountedPrice: discountedPrice,
 			ActivePromotion: activePromotion,
-		})
+		}
+		if priceList != nil {
+			response.PriceListID = &priceList.ID
+		}
+		productResponses = append(productResponses, response)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -110,6 +144,7 @@ func StoreProduct(c *gin.Context) {
 		SKU:              data.SKU,
 		Price:            data.Price,
 		CategoryID:       data.CategoryID,
+		TaxClassID:       data.TaxClassID,
 		Quantity:         0, // Initial quantity
 		ReservedQuantity: 0,
 	}
@@ -123,12 +158,14 @@ func StoreProduct(c *gin.Context) {
 }
 
 // @Summary Get a product by ID
-// @Description Get a single product by its ID.
+// @Description Get a single product by its ID, priced from the given or the default price list.
 // @Tags Products
 // @Produce  json
 // @Security BearerAuth
 // @Param   id      path    int     true        "Product ID"
+// @Param   price_list_id query int false       "Price list to price the product from"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Router /products/{id} [get]
@@ -145,16 +182,26 @@ func GetProductByID(c *gin.Context) {
 		return
 	}
 
-	discountedPrice, activePromotion := utils.CalculateTotalPrice(product, 1)
+	priceList, err := productPriceList(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	discountedPrice, activePromotion := utils.CalculateTotalPrice(product, 1, priceList)
 
 	productResponse := ProductResponse{
 		Product:          product,
 		CategoryName:     product.Category.Name,
 		Quantity:         product.Quantity,
 		ReservedQuantity: product.ReservedQuantity,
+		UnitPrice:        utils.PriceListUnitPrice(priceList, product, 1),
 		DiscountedPrice:  discountedPrice,
 		ActivePromotion:  activePromotion,
 	}
+	if priceList != nil {
+		productResponse.PriceListID = &priceList.ID
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": productResponse,
@@ -208,6 +255,7 @@ func UpdateProductByID(c *gin.Context) {
 	product.SKU = data.SKU
 	product.Price = data.Price
 	product.CategoryID = data.CategoryID
+	product.TaxClassID = data.TaxClassID
 
 	database.DB.Save(&product)
 
@@ -1,25 +1,28 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"pos/database"
 	"pos/models"
+	"pos/validators"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ProductPromotionInput struct {
-	ProductID        uint      `json:"product_id"`
-	PromotionType    string    `json:"promotion_type"` // e.g., "percentage_discount", "fixed_discount", "buy_x_get_y", "bundle_price"
-	DiscountValue    float64   `json:"discount_value,omitempty"`
-	BuyProductID     *uint     `json:"buy_product_id,omitempty"`
-	GetProductID     *uint     `json:"get_product_id,omitempty"`
-	RequiredQuantity *int      `json:"required_quantity,omitempty"` // For "bundle_price" or "buy_x_get_y"
-	PromoPrice       *float64  `json:"promo_price,omitempty"`       // For "bundle_price"
-	StartDate        time.Time `json:"start_date"`
-	EndDate          time.Time `json:"end_date"`
+	ProductID        uint          `json:"product_id" binding:"required,exists=products-id"`
+	PromotionType    string        `json:"promotion_type" binding:"required,oneof=percentage_discount fixed_discount buy_x_get_y bundle_price"`
+	DiscountValue    models.Money  `json:"discount_value,omitempty"`
+	BuyProductID     *uint         `json:"buy_product_id,omitempty" binding:"omitempty,exists=products-id"`
+	GetProductID     *uint         `json:"get_product_id,omitempty" binding:"omitempty,exists=products-id"`
+	RequiredQuantity *int          `json:"required_quantity,omitempty"` // For "bundle_price" or "buy_x_get_y"
+	PromoPrice       *models.Money `json:"promo_price,omitempty"`       // For "bundle_price"
+	StartDate        time.Time     `json:"start_date" binding:"required"`
+	EndDate          time.Time     `json:"end_date" binding:"required"`
+	AllowStacking    bool          `json:"allow_stacking"`
 }
 
 type ProductPromotionsResponse struct {
@@ -30,53 +33,113 @@ type ProductPromotionResponse struct {
 	Data models.ProductPromotion `json:"data"`
 }
 
+// validateProductPromotion runs the type-specific checks for a promotion and
+// rejects overlaps with other promotions of the same type on the same product.
+// excludeID is the promotion being updated, or nil on create.
+func validateProductPromotion(input ProductPromotionInput, excludeID *uint) ([]models.FieldError, error) {
+	var fieldErrors []models.FieldError
+
+	switch input.PromotionType {
+	case "buy_x_get_y":
+		if input.BuyProductID == nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "buy_product_id", Message: "is required for buy_x_get_y promotion"})
+		} else if *input.BuyProductID != input.ProductID {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "buy_product_id", Message: "must match product_id"})
+		}
+		if input.GetProductID == nil {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "get_product_id", Message: "is required for buy_x_get_y promotion"})
+		}
+	case "percentage_discount", "fixed_discount":
+		if input.DiscountValue <= 0 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "discount_value", Message: "must be greater than 0 for discount promotions"})
+		} else if input.PromotionType == "percentage_discount" && input.DiscountValue > models.MoneyFromUnits(100) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "discount_value", Message: "must not exceed 100 for percentage_discount promotion"})
+		}
+	case "bundle_price":
+		if input.RequiredQuantity == nil || *input.RequiredQuantity <= 0 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "required_quantity", Message: "must be greater than 0 for bundle_price promotion"})
+		}
+		if input.PromoPrice == nil || *input.PromoPrice <= 0 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "promo_price", Message: "must be greater than 0 for bundle_price promotion"})
+		}
+	}
+
+	if input.EndDate.Before(input.StartDate) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "end_date", Message: "cannot be before start_date"})
+	}
+
+	if len(fieldErrors) > 0 {
+		return fieldErrors, nil
+	}
+
+	// Overlapping promotions of the same type are only allowed when both sides allow stacking
+	query := database.DB.Model(&models.ProductPromotion{}).
+		Where("product_id = ? AND promotion_type = ?", input.ProductID, input.PromotionType).
+		Where("start_date <= ? AND end_date >= ?", input.EndDate, input.StartDate)
+	if excludeID != nil {
+		query = query.Where("id != ?", *excludeID)
+	}
+	if input.AllowStacking {
+		query = query.Where("allow_stacking = ?", false)
+	}
+
+	var overlapping models.ProductPromotion
+	if err := query.Order("start_date").Limit(1).Find(&overlapping).Error; err != nil {
+		return nil, err
+	}
+	if overlapping.ID != 0 {
+		return []models.FieldError{{
+			Field:   "start_date",
+			Message: fmt.Sprintf("overlaps with %s promotion %d on the same product", overlapping.PromotionType, overlapping.ID),
+		}}, nil
+	}
+
+	return nil, nil
+}
+
 // CreatePromotion handles the creation of a new promotion
 // @Summary Create a new product promotion
-// @Description Create a new product promotion. Admin only.
+// @Description Create a new product promotion. Admin only. Promotions of the same type on the same product may not overlap unless both set allow_stacking. Stacking only lifts this check: a product gets one promotion at checkout, the active one that started first.
 // @Tags Promotions
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param   promotion body    ProductPromotionInput true "Promotion data"
 // @Success 201 {object} models.MessageResponse
-// @Failure 400 {object} models.MessageResponse
+// @Failure 400 {object} models.ValidationErrorResponse
 // @Failure 401 {object} models.MessageResponse
 // @Failure 500 {object} models.MessageResponse
 // @Router /product-promotions [post]
 func CreateProductPromotion(c *gin.Context) {
-	var promotion ProductPromotionInput
-	if err := c.ShouldBindJSON(&promotion); err != nil {
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid request body"})
+	var input ProductPromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{Message: "Invalid request body", Errors: validators.FieldErrors(err)})
 		return
 	}
 
-	// Validate promotion type and data
-	switch promotion.PromotionType {
-	case "buy_x_get_y":
-		if promotion.BuyProductID == nil || promotion.GetProductID == nil {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "BuyProductID and GetProductID are required for buy_x_get_y promotion"})
-			return
-		}
-	case "percentage_discount", "fixed_discount":
-		if promotion.DiscountValue <= 0 {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "DiscountValue must be greater than 0 for discount promotions"})
-			return
-		}
-	case "bundle_price":
-		if promotion.RequiredQuantity == nil || promotion.PromoPrice == nil || *promotion.RequiredQuantity <= 0 || *promotion.PromoPrice <= 0 {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "RequiredQuantity and PromoPrice must be greater than 0 for bundle_price promotion"})
-			return
-		}
-	default:
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid promotion type"})
+	fieldErrors, err := validateProductPromotion(input, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
 		return
 	}
-
-	if promotion.EndDate.Before(promotion.StartDate) {
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "End date cannot be before start date"})
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{Message: "Invalid promotion", Errors: fieldErrors})
 		return
 	}
 
+	promotion := models.ProductPromotion{
+		ProductID:        input.ProductID,
+		PromotionType:    input.PromotionType,
+		DiscountValue:    input.DiscountValue,
+		BuyProductID:     input.BuyProductID,
+		GetProductID:     input.GetProductID,
+		RequiredQuantity: input.RequiredQuantity,
+		PromoPrice:       input.PromoPrice,
+		StartDate:        input.StartDate,
+		EndDate:          input.EndDate,
+		AllowStacking:    input.AllowStacking,
+	}
+
 	if err := database.DB.Create(&promotion).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not create promotion"})
 		return
@@ -121,22 +184,23 @@ func GetProductPromotion(c *gin.Context) {
 
 // UpdatePromotion handles updating an existing promotion
 // @Summary Update a product promotion by ID
-// @Description Update a product promotion's details by its ID. Admin only.
+// @Description Update a product promotion's details by its ID. Admin only. Promotions of the same type on the same product may not overlap unless both set allow_stacking. Stacking only lifts this check: a product gets one promotion at checkout, the active one that started first.
 // @Tags Promotions
 // @Accept  json
 // @Produce  json
 // @Security BearerAuth
 // @Param   id      path    int     true        "Promotion ID"
-// @Param   promotion body    models.ProductPromotion true "Promotion data to update"
+// @Param   promotion body    ProductPromotionInput true "Promotion data to update"
 // @Success 200 {object} models.MessageResponse
-// @Failure 400 {object} models.MessageResponse
+// @Failure 400 {object} models.ValidationErrorResponse
 // @Failure 404 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
 // @Router /product-promotions/{id} [put]
 func UpdateProductPromotion(c *gin.Context) {
 	id := c.Param("id")
-	var promotion models.ProductPromotion
-	if err := c.ShouldBindJSON(&promotion); err != nil {
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid request body"})
+	var input ProductPromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{Message: "Invalid request body", Errors: validators.FieldErrors(err)})
 		return
 	}
 
@@ -146,44 +210,28 @@ func UpdateProductPromotion(c *gin.Context) {
 		return
 	}
 
-	// Update fields
-	existingPromotion.ProductID = promotion.ProductID
-	existingPromotion.PromotionType = promotion.PromotionType
-	existingPromotion.DiscountValue = promotion.DiscountValue
-	existingPromotion.BuyProductID = promotion.BuyProductID
-	existingPromotion.GetProductID = promotion.GetProductID
-	existingPromotion.RequiredQuantity = promotion.RequiredQuantity
-	existingPromotion.PromoPrice = promotion.PromoPrice
-	existingPromotion.StartDate = promotion.StartDate
-	existingPromotion.EndDate = promotion.EndDate
-
-	// Validate promotion type and data
-	switch existingPromotion.PromotionType {
-	case "buy_x_get_y":
-		if existingPromotion.BuyProductID == nil || existingPromotion.GetProductID == nil {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "BuyProductID and GetProductID are required for buy_x_get_y promotion"})
-			return
-		}
-	case "percentage_discount", "fixed_discount":
-		if existingPromotion.DiscountValue <= 0 {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "DiscountValue must be greater than 0 for discount promotions"})
-			return
-		}
-	case "bundle_price":
-		if existingPromotion.RequiredQuantity == nil || existingPromotion.PromoPrice == nil || *existingPromotion.RequiredQuantity <= 0 || *existingPromotion.PromoPrice <= 0 {
-			c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "RequiredQuantity and PromoPrice must be greater than 0 for bundle_price promotion"})
-			return
-		}
-	default:
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Invalid promotion type"})
+	fieldErrors, err := validateProductPromotion(input, &existingPromotion.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
 		return
 	}
-
-	if existingPromotion.EndDate.Before(existingPromotion.StartDate) {
-		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "End date cannot be before start date"})
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{Message: "Invalid promotion", Errors: fieldErrors})
 		return
 	}
 
+	// Update fields
+	existingPromotion.ProductID = input.ProductID
+	existingPromotion.PromotionType = input.PromotionType
+	existingPromotion.DiscountValue = input.DiscountValue
+	existingPromotion.BuyProductID = input.BuyProductID
+	existingPromotion.GetProductID = input.GetProductID
+	existingPromotion.RequiredQuantity = input.RequiredQuantity
+	existingPromotion.PromoPrice = input.PromoPrice
+	existingPromotion.StartDate = input.StartDate
+	existingPromotion.EndDate = input.EndDate
+	existingPromotion.AllowStacking = input.AllowStacking
+
 	if err := database.DB.Save(&existingPromotion).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not update promotion"})
 		return
@@ -97,7 +97,7 @@ func UpdateProfile(c *gin.Context) {
 }
 
 // @Summary Update user password
-// @Description Update the password of the currently logged-in user.
+// @Description Update the password of the currently logged-in user. All sessions and access tokens of the user are revoked, so they log in again.
 // @Tags Profile
 // @Accept  json
 // @Produce  json
@@ -133,6 +133,12 @@ func UpdateProfilePassword(c *gin.Context) {
 	}
 	user.Password = string(password)
 	database.DB.Save(&user)
+
+	// Sign out every device; the user logs in again with the new password
+	if err := invalidateUserTokens(database.DB, user.ID, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not revoke sessions"})
+		return
+	}
 	c.JSON(http.StatusOK, user)
 
 }
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"pos/database"
+	"pos/models"
+	"pos/receipt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrderReceipt handles rendering the receipt of an order
+// @Summary Get an order receipt
+// @Description Render the receipt of an order with the store header, line items with discounts and free items, cart discount, taxes, payments and totals. The escpos format is raw ESC/POS bytes for thermal printers.
+// @Tags Orders
+// @Produce  plain
+// @Produce  application/pdf
+// @Produce  application/octet-stream
+// @Security BearerAuth
+// @Param   id      path    int     true        "Order ID"
+// @Param   format  query   string  false       "Receipt format: txt (default), pdf or escpos"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /orders/{id}/receipt [get]
+func GetOrderReceipt(c *gin.Context) {
+	id := c.Param("id")
+
+	format := c.DefaultQuery("format", "txt")
+	if format != "txt" && format != "pdf" && format != "escpos" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid format. Must be 'txt', 'pdf' or 'escpos'"})
+		return
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var order models.Order
+	if err := scopeOrders(database.DB, user).Preload("OrderItems.Product").Preload("Taxes").Preload("Payments").First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Order not found", "data": err.Error()})
+		return
+	}
+
+	r := receipt.FromOrder(order)
+
+	var body []byte
+	var contentType, extension string
+	switch format {
+	case "pdf":
+		body, contentType, extension = r.PDF(), "application/pdf", "pdf"
+	case "escpos":
+		body, contentType, extension = r.ESCPOS(), "application/octet-stream", "bin"
+	default:
+		body, contentType, extension = r.Text(), "text/plain; charset=utf-8", "txt"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%d.%s"`, order.ID, extension))
+	c.Data(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pos/config"
+	"pos/database"
+	"pos/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errSessionRotated is returned by rotateSession when the session was already
+// exchanged for a new one, which means its refresh token was used twice.
+var errSessionRotated = errors.New("session already rotated")
+
+type SessionsResponse struct {
+	Data []models.Session `json:"data"`
+}
+
+// refreshTokenTTL returns how long a refresh token can be used.
+func refreshTokenTTL() time.Duration {
+	days, err := strconv.Atoi(config.LoadConfig("REFRESH_TOKEN_TTL_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken returns the hash a refresh token is stored and looked up by.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession generates a refresh token for session and stores the session.
+func startSession(db *gorm.DB, session *models.Session) (string, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	session.TokenHash = hashRefreshToken(refreshToken)
+	session.ExpiresAt = time.Now().Add(refreshTokenTTL())
+	if err := db.Create(session).Error; err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// rotateSession replaces session with a new session in the same family and
+// returns its refresh token.
+func rotateSession(c *gin.Context, session *models.Session) (string, error) {
+	var refreshToken string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// Only one request can rotate a session, even when two arrive at once
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", session.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errSessionRotated
+		}
+
+		next := models.Session{
+			UserID:     session.UserID,
+			FamilyID:   session.FamilyID,
+			DeviceName: session.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IPAddress:  c.ClientIP(),
+		}
+		var err error
+		if refreshToken, err = startSession(tx, &next); err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).Where("id = ?", session.ID).Update("replaced_by_id", next.ID).Error
+	})
+	return refreshToken, err
+}
+
+// revokeSessionFamily revokes every session rotated from the same login.
+func revokeSessionFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// activeSessions returns the sessions of a user that can still be refreshed,
+// one per signed in device.
+func activeSessions(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := database.DB.
+		Where("user_id = ? AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("updated_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// revokeUserSession revokes the session with the given ID when it belongs to the user.
+func revokeUserSession(c *gin.Context, userID uint, sessionID string) {
+	var session models.Session
+	database.DB.Where("user_id = ?", userID).First(&session, sessionID)
+
+	if session.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Session not found"})
+		return
+	}
+
+	if err := revokeSessionFamily(database.DB, session.FamilyID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Session revoked"})
+}
+
+// @Summary Get the current user's sessions
+// @Description Get the signed in devices of the currently logged-in user.
+// @Tags Profile
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} SessionsResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /profile/sessions [get]
+func GetProfileSessions(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	sessions, err := activeSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, SessionsResponse{Data: sessions})
+}
+
+// @Summary Revoke one of the current user's sessions
+// @Description Sign out a device of the currently logged-in user. Its refresh token stops working.
+// @Tags Profile
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Session ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /profile/sessions/{id} [delete]
+func RevokeProfileSession(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	revokeUserSession(c, userID, c.Param("id"))
+}
+
+// @Summary Get a user's sessions
+// @Description Get the signed in devices of a user by their ID. Admin only.
+// @Tags Users
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "User ID"
+// @Success 200 {object} SessionsResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /users/{id}/sessions [get]
+func GetUserSessions(c *gin.Context) {
+	var user models.User
+	database.DB.First(&user, c.Param("id"))
+
+	if user.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "User not found"})
+		return
+	}
+
+	sessions, err := activeSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, SessionsResponse{Data: sessions})
+}
+
+// @Summary Revoke a user's session
+// @Description Sign out one device of a user. Admin only.
+// @Tags Users
+// @Produce  json
+// @Security BearerAuth
+// @Param   id         path    int     true        "User ID"
+// @Param   session_id path    int     true        "Session ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /users/{id}/sessions/{session_id} [delete]
+func RevokeUserSession(c *gin.Context) {
+	var user models.User
+	database.DB.First(&user, c.Param("id"))
+
+	if user.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "User not found"})
+		return
+	}
+
+	revokeUserSession(c, user.ID, c.Param("session_id"))
+}
+
+// @Summary Revoke all of a user's sessions
+// @Description Sign out every device of a user, including the access tokens already issued. Admin only.
+// @Tags Users
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "User ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /users/{id}/sessions [delete]
+func RevokeUserSessions(c *gin.Context) {
+	var user models.User
+	database.DB.First(&user, c.Param("id"))
+
+	if user.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "User not found"})
+		return
+	}
+
+	if err := invalidateUserTokens(database.DB, user.ID, true); err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Sessions revoked"})
+}
+
+// invalidateUserTokens bumps the user's token version, so that access tokens
+// issued so far are rejected, and optionally revokes all of their sessions.
+func invalidateUserTokens(db *gorm.DB, userID uint, revokeSessions bool) error {
+	if err := db.Model(&models.User{}).Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	if !revokeSessions {
+		return nil
+	}
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
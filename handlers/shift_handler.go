@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pos/config"
+	"pos/database"
+	"pos/models"
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OpenShiftInput struct {
+	OpeningFloat models.Money `json:"opening_float" binding:"gte=0"`
+	Notes        string       `json:"notes"`
+}
+
+type ShiftCashEventInput struct {
+	Type   string       `json:"type" binding:"required,oneof=cash_in cash_out"`
+	Amount models.Money `json:"amount" binding:"required,gt=0"`
+	Reason string       `json:"reason" binding:"required"`
+}
+
+type CountedAmountInput struct {
+	Method string       `json:"method" binding:"required"`
+	Amount models.Money `json:"amount" binding:"gte=0"`
+}
+
+type CloseShiftInput struct {
+	Counted []CountedAmountInput `json:"counted" binding:"required,min=1,dive"` // Must include cash; other methods default to their expected amount
+	Notes   string               `json:"notes"`
+}
+
+type ShiftReportRow struct {
+	Method     string        `json:"method"`
+	Payments   int64         `json:"payments"`
+	Sales      models.Money  `json:"sales"`
+	Expected   models.Money  `json:"expected"`
+	Counted    *models.Money `json:"counted"`    // Only known once the shift is closed
+	Difference *models.Money `json:"difference"` // Counted - Expected
+}
+
+// ShiftReport is an X report while the shift is open and a Z report once it is closed.
+type ShiftReport struct {
+	Kind       string                  `json:"kind"` // "X" or "Z"
+	Shift      models.Shift            `json:"shift"`
+	Orders     int64                   `json:"orders"`
+	Rows       []ShiftReportRow        `json:"rows"`
+	CashIn     models.Money            `json:"cash_in"`
+	CashOut    models.Money            `json:"cash_out"`
+	CashEvents []models.ShiftCashEvent `json:"cash_events"`
+}
+
+type ShiftReportResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Data    ShiftReport `json:"data"`
+}
+
+// requireOpenShift reports whether staff can only create orders during an open shift.
+func requireOpenShift() bool {
+	return config.LoadConfig("REQUIRE_OPEN_SHIFT") == "true"
+}
+
+// findOpenShift returns the user's open shift, or nil when they have none.
+func findOpenShift(tx *gorm.DB, userID uint) (*models.Shift, error) {
+	var shift models.Shift
+	err := tx.Where("user_id = ? AND status = ?", userID, models.ShiftStatusOpen).First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// assignPaymentShifts ties each payment to the open shift of the user who took it.
+func assignPaymentShifts(tx *gorm.DB, payments []models.Payment) error {
+	shifts := map[uint]*uint{}
+	for i := range payments {
+		shiftID, ok := shifts[payments[i].UserID]
+		if !ok {
+			shift, err := findOpenShift(tx, payments[i].UserID)
+			if err != nil {
+				return err
+			}
+			if shift != nil {
+				shiftID = &shift.ID
+			}
+			shifts[payments[i].UserID] = shiftID
+		}
+		payments[i].ShiftID = shiftID
+	}
+	return nil
+}
+
+// buildShiftReport adds up the payments taken and the cash moved during the
+// shift. Payments stay in the shift that took them when their order is
+// cancelled or refunded later; cash handed back is a cash out of the drawer it
+// left. A closed shift is reported from the totals stored when it was closed,
+// so its Z report never changes.
+func buildShiftReport(tx *gorm.DB, shift models.Shift) (ShiftReport, error) {
+	report := ShiftReport{Kind: "X", Shift: shift, Rows: []ShiftReportRow{}, CashEvents: []models.ShiftCashEvent{}}
+
+	// Cash events can only be added while the shift is open
+	if err := tx.Where("shift_id = ?", shift.ID).Order("id").Find(&report.CashEvents).Error; err != nil {
+		return report, err
+	}
+	for _, event := range report.CashEvents {
+		if event.Type == models.ShiftCashIn {
+			report.CashIn += event.Amount
+		} else {
+			report.CashOut += event.Amount
+		}
+	}
+
+	if shift.Status == models.ShiftStatusClosed {
+		report.Kind = "Z"
+		report.Orders = shift.OrderCount
+
+		var totals []models.ShiftTotal
+		if err := tx.Where("shift_id = ?", shift.ID).Order("id").Find(&totals).Error; err != nil {
+			return report, err
+		}
+		for _, total := range totals {
+			counted, difference := total.Counted, total.Difference
+			report.Rows = append(report.Rows, ShiftReportRow{
+				Method:     total.Method,
+				Payments:   total.Payments,
+				Sales:      total.Sales,
+				Expected:   total.Expected,
+				Counted:    &counted,
+				Difference: &difference,
+			})
+		}
+		return report, nil
+	}
+
+	if err := tx.Model(&models.Order{}).
+		Where("shift_id = ? AND status <> ?", shift.ID, models.OrderStatusCancelled).
+		Count(&report.Orders).Error; err != nil {
+		return report, err
+	}
+
+	var sales []struct {
+		Method   string
+		Payments int64
+		Sales    models.Money
+	}
+	if err := tx.Model(&models.Payment{}).
+		Select("method, COUNT(*) AS payments, COALESCE(SUM(amount), 0) AS sales").
+		Where("shift_id = ?", shift.ID).
+		Group("method").
+		Order("method").
+		Scan(&sales).Error; err != nil {
+		return report, err
+	}
+
+	// The cash row is always present, since the float and cash events count towards it
+	report.Rows = append(report.Rows, ShiftReportRow{Method: models.PaymentMethodCash})
+	for _, sale := range sales {
+		if sale.Method == models.PaymentMethodCash {
+			report.Rows[0].Payments = sale.Payments
+			report.Rows[0].Sales = sale.Sales
+			continue
+		}
+		report.Rows = append(report.Rows, ShiftReportRow{Method: sale.Method, Payments: sale.Payments, Sales: sale.Sales, Expected: sale.Sales})
+	}
+	report.Rows[0].Expected = shift.OpeningFloat + report.Rows[0].Sales + report.CashIn - report.CashOut
+
+	return report, nil
+}
+
+// recordCashRefund takes the cash paid on a cancelled or refunded order out of
+// a drawer: the open shift of the user handing it back, or else the shift that
+// took the cash if it is still open. Cash taken outside of a shift never went
+// into a drawer and is not recorded.
+func recordCashRefund(tx *gorm.DB, order models.Order, userID uint) error {
+	var payments []models.Payment
+	if err := tx.Where("order_id = ? AND method = ? AND shift_id IS NOT NULL", order.ID, models.PaymentMethodCash).
+		Order("id").Find(&payments).Error; err != nil {
+		return err
+	}
+	var amount models.Money
+	for _, payment := range payments {
+		amount += payment.Amount
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	shift, err := findOpenShift(tx, userID)
+	if err != nil {
+		return err
+	}
+	if shift == nil {
+		var taken models.Shift
+		err := tx.Where("id = ? AND status = ?", *payments[0].ShiftID, models.ShiftStatusOpen).First(&taken).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &httpError{Status: http.StatusBadRequest, Message: "Open a shift to hand back the cash paid on this order", Err: fmt.Errorf("%s in cash to refund", amount)}
+		}
+		if err != nil {
+			return err
+		}
+		shift = &taken
+	}
+
+	return tx.Create(&models.ShiftCashEvent{
+		ShiftID: shift.ID,
+		Type:    models.ShiftCashOut,
+		Amount:  amount,
+		Reason:  fmt.Sprintf("Refund of order %d", order.ID),
+		OrderID: &order.ID,
+		UserID:  userID,
+	}).Error
+}
+
+// @Summary Open a shift
+// @Description Open a till session for the current user with the cash float in the drawer. Orders and payments taken by the user are tied to the shift until it is closed. Admin or cashier only.
+// @Tags Shifts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   shift   body    OpenShiftInput true "Opening float"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /shifts [post]
+func OpenShift(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input OpenShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	existing, err := findOpenShift(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "You already have an open shift", "data": existing})
+		return
+	}
+
+	shift := models.Shift{
+		UserID:       userID,
+		Status:       models.ShiftStatusOpen,
+		OpenedAt:     time.Now(),
+		OpeningFloat: input.OpeningFloat,
+		Notes:        input.Notes,
+	}
+	if err := database.DB.Create(&shift).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not open shift", "data": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Shift opened", "data": shift})
+}
+
+// @Summary Get the current shift
+// @Description Get the X report of the current user's open shift: payments taken per method, cash in and out, and the cash expected in the drawer. Admin or cashier only.
+// @Tags Shifts
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} ShiftReportResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /shifts/current [get]
+func GetCurrentShift(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	shift, err := findOpenShift(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+		return
+	}
+	if shift == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "No open shift"})
+		return
+	}
+
+	report, err := buildShiftReport(database.DB, *shift)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not build shift report", "data": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ShiftReportResponse{Status: "success", Message: "Shift fetched", Data: report})
+}
+
+// @Summary Record cash in or out
+// @Description Record cash put into or taken out of the drawer of the current user's open shift, e.g. extra change or a bank drop. Admin or cashier only.
+// @Tags Shifts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   event   body    ShiftCashEventInput true "Cash movement"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /shifts/current/cash-events [post]
+func AddShiftCashEvent(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input ShiftCashEventInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	shift, err := findOpenShift(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Database error", "data": err.Error()})
+		return
+	}
+	if shift == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "No open shift"})
+		return
+	}
+
+	event := models.ShiftCashEvent{
+		ShiftID: shift.ID,
+		Type:    input.Type,
+		Amount:  input.Amount,
+		Reason:  input.Reason,
+		UserID:  userID,
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not record cash event", "data": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Cash event recorded", "data": event})
+}
+
+// @Summary Close the current shift
+// @Description Close the current user's open shift with the amounts counted per payment method and get its Z report. Cash must be counted; other methods default to their expected amount. Admin or cashier only.
+// @Tags Shifts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   close   body    CloseShiftInput true "Counted amounts"
+// @Success 200 {object} ShiftReportResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /shifts/current/close [post]
+func CloseShift(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+
+	var input CloseShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid request body", "data": err.Error()})
+		return
+	}
+
+	counted := map[string]models.Money{}
+	for _, amount := range input.Counted {
+		counted[amount.Method] += amount.Amount
+	}
+	if _, ok := counted[models.PaymentMethodCash]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "The counted cash is required"})
+		return
+	}
+
+	var report ShiftReport
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var shift models.Shift
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND status = ?", userID, models.ShiftStatusOpen).
+			First(&shift).Error; err != nil {
+			return &httpError{Status: http.StatusNotFound, Message: "No open shift", Err: err}
+		}
+
+		current, err := buildShiftReport(tx, shift)
+		if err != nil {
+			return err
+		}
+
+		var totals []models.ShiftTotal
+		for _, row := range current.Rows {
+			amount, ok := counted[row.Method]
+			if !ok {
+				amount = row.Expected
+			}
+			delete(counted, row.Method)
+			totals = append(totals, models.ShiftTotal{
+				ShiftID:    shift.ID,
+				Method:     row.Method,
+				Payments:   row.Payments,
+				Sales:      row.Sales,
+				Expected:   row.Expected,
+				Counted:    amount,
+				Difference: amount - row.Expected,
+			})
+		}
+		// Methods that were counted but took no payments during the shift
+		for method, amount := range counted {
+			totals = append(totals, models.ShiftTotal{ShiftID: shift.ID, Method: method, Counted: amount, Difference: amount})
+		}
+		if err := tx.Create(&totals).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		countedCash := totals[0].Counted
+		shift.Status = models.ShiftStatusClosed
+		shift.ClosedAt = &now
+		shift.ExpectedCash = current.Rows[0].Expected
+		shift.CountedCash = &countedCash
+		shift.CashDifference = countedCash - shift.ExpectedCash
+		shift.OrderCount = current.Orders
+		if input.Notes != "" {
+			shift.Notes = input.Notes
+		}
+		if err := tx.Save(&shift).Error; err != nil {
+			return err
+		}
+
+		report, err = buildShiftReport(tx, shift)
+		return err
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ShiftReportResponse{Status: "success", Message: "Shift closed", Data: report})
+}
+
+// @Summary Get all shifts
+// @Description Get a list of shifts, newest first. Admin only.
+// @Tags Shifts
+// @Produce  json
+// @Security BearerAuth
+// @Param   user_id   query    int     false        "Filter by cashier"
+// @Param   status    query    string  false        "Filter by status: open or closed"
+// @Param   page      query    int     false        "Page number"
+// @Param   limit     query    int     false        "Number of items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /shifts [get]
+func GetShifts(c *gin.Context) {
+	page, _ := utils.GetInt(c.DefaultQuery("page", "1"))
+	limit, _ := utils.GetInt(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Shift{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var shifts []models.Shift
+	query.Order("id DESC").Limit(limit).Offset(offset).Find(&shifts)
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Shifts fetched", "data": shifts, "total": total, "page": page, "limit": limit})
+}
+
+// @Summary Get a shift report
+// @Description Get the X report of an open shift or the Z report of a closed shift, with expected versus counted amounts per payment method. Admin only.
+// @Tags Shifts
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Shift ID"
+// @Success 200 {object} ShiftReportResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /shifts/{id}/report [get]
+func GetShiftReport(c *gin.Context) {
+	id := c.Param("id")
+
+	var shift models.Shift
+	if err := database.DB.First(&shift, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Shift not found", "data": err.Error()})
+		return
+	}
+
+	report, err := buildShiftReport(database.DB, shift)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Could not build shift report", "data": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ShiftReportResponse{Status: "success", Message: fmt.Sprintf("%s report fetched", report.Kind), Data: report})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"pos/database"
+	"pos/models"
+
+	"pos/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxClassInput struct {
+	Name      string       `json:"name" binding:"required"`
+	Rate      models.Money `json:"rate" binding:"gte=0,lte=10000"` // Percent with two decimals, 0.00 - 100.00
+	Inclusive bool         `json:"inclusive"`
+}
+
+type TaxClassesResponse struct {
+	Data []models.TaxClass `json:"data"`
+}
+
+type TaxClassResponse struct {
+	Data models.TaxClass `json:"data"`
+}
+
+// @Summary Get all tax classes
+// @Description Get a list of all tax classes.
+// @Tags Tax Classes
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} TaxClassesResponse
+// @Router /tax-classes [get]
+func GetTaxClasses(c *gin.Context) {
+	var taxClasses []models.TaxClass
+	database.DB.Order("name").Find(&taxClasses)
+	c.JSON(http.StatusOK, TaxClassesResponse{Data: taxClasses})
+}
+
+// @Summary Create a new tax class
+// @Description Create a new tax class. Products use their own tax class, or their category's when they have none. Admin only.
+// @Tags Tax Classes
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   tax_class body    TaxClassInput true "Tax class data"
+// @Success 201 {object} models.MessageResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 500 {object} models.MessageResponse
+// @Router /tax-classes [post]
+func StoreTaxClass(c *gin.Context) {
+	var data TaxClassInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	isDup, err := utils.IsDuplicate[models.TaxClass](database.DB, "name", data.Name, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Tax class already exists"})
+		return
+	}
+
+	taxClass := models.TaxClass{
+		Name:      data.Name,
+		Rate:      data.Rate,
+		Inclusive: data.Inclusive,
+	}
+
+	if err := database.DB.Create(&taxClass).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Could not create tax class"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.MessageResponse{Message: "Tax class created"})
+}
+
+// @Summary Get a tax class by ID
+// @Description Get a single tax class by its ID.
+// @Tags Tax Classes
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Tax class ID"
+// @Success 200 {object} TaxClassResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /tax-classes/{id} [get]
+func GetTaxClassByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var taxClass models.TaxClass
+	database.DB.First(&taxClass, id)
+
+	if taxClass.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Tax class not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, TaxClassResponse{Data: taxClass})
+}
+
+// @Summary Update a tax class by ID
+// @Description Update a tax class by its ID. Orders already placed keep the rate they were taxed at. Admin only.
+// @Tags Tax Classes
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Tax class ID"
+// @Param   tax_class body    TaxClassInput true "Tax class data to update"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /tax-classes/{id} [put]
+func UpdateTaxClassByID(c *gin.Context) {
+	id := c.Param("id")
+	var data TaxClassInput
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: err.Error()})
+		return
+	}
+
+	var taxClass models.TaxClass
+	database.DB.First(&taxClass, id)
+
+	if taxClass.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Tax class not found"})
+		return
+	}
+
+	isDup, err := utils.IsDuplicate[models.TaxClass](database.DB, "name", data.Name, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.MessageResponse{Message: "Database error"})
+		return
+	}
+	if isDup {
+		c.JSON(http.StatusBadRequest, models.MessageResponse{Message: "Tax class name already exists"})
+		return
+	}
+
+	taxClass.Name = data.Name
+	taxClass.Rate = data.Rate
+	taxClass.Inclusive = data.Inclusive
+
+	database.DB.Save(&taxClass)
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Tax class updated"})
+}
+
+// @Summary Delete a tax class by ID
+// @Description Delete a tax class by its ID. Admin only.
+// @Tags Tax Classes
+// @Produce  json
+// @Security BearerAuth
+// @Param   id      path    int     true        "Tax class ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 401 {object} models.MessageResponse
+// @Failure 404 {object} models.MessageResponse
+// @Router /tax-classes/{id} [delete]
+func DeleteTaxClassByID(c *gin.Context) {
+	id := c.Param("id")
+
+	var taxClass models.TaxClass
+	database.DB.First(&taxClass, id)
+
+	if taxClass.ID == 0 {
+		c.JSON(http.StatusNotFound, models.MessageResponse{Message: "Tax class not found"})
+		return
+	}
+
+	database.DB.Delete(&taxClass, id)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Tax class deleted"})
+}
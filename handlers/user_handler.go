@@ -14,7 +14,7 @@ import (
 type UpdateUserInput struct {
 	Username string  `json:"username" binding:"required"`
 	Name     string  `json:"name" binding:"required"`
-	Role     string  `json:"role" binding:"required,oneof=admin user"`
+	Role     string  `json:"role" binding:"required,oneof=admin cashier user"`
 	Password *string `json:"password"`
 }
 
@@ -76,7 +76,7 @@ func GetUserByID(c *gin.Context) {
 }
 
 // @Summary Update a user by ID
-// @Description Update a user's details by their ID. Admin only.
+// @Description Update a user's details by their ID. Changing the role or password revokes the access tokens already issued to the user, and a password change also signs out their sessions. Admin only.
 // @Tags Users
 // @Accept  json
 // @Produce  json
@@ -118,6 +118,9 @@ func UpdateUserByID(c *gin.Context) {
 		return
 	}
 
+	// Tokens issued before a role or password change must not be used any more
+	invalidate := user.Role != data.Role || data.Password != nil
+
 	user.Username = data.Username
 	user.Name = data.Name
 	user.Role = data.Role
@@ -132,11 +135,18 @@ func UpdateUserByID(c *gin.Context) {
 
 	database.DB.Save(&user)
 
+	if invalidate {
+		if err := invalidateUserTokens(database.DB, user.ID, data.Password != nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not revoke tokens"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, user)
 }
 
 // @Summary Delete a user by ID
-// @Description Delete a user by their ID. Admin only.
+// @Description Delete a user by their ID and sign out their sessions. Admin only.
 // @Tags Users
 // @Produce  json
 // @Security BearerAuth
@@ -159,6 +169,10 @@ func DeleteUserByID(c *gin.Context) {
 	}
 
 	database.DB.Delete(&user, id)
+	if err := invalidateUserTokens(database.DB, user.ID, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not revoke sessions"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User deleted",
 	})
@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"pos/database"
+	"pos/handlers"
 	"pos/routes"
+	"time"
 
 	"pos/validators"
 
@@ -41,6 +43,8 @@ func main() {
 
 	routes.SetupRoutes(app)
 
+	go handlers.RunMaintenance(time.Minute)
+
 	// Swagger route
 	app.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -2,12 +2,12 @@ package middleware
 
 import (
 	"net/http"
-	"pos/config"
-	"pos/handlers"
+	"pos/database"
+	"pos/models"
+	"pos/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	jwtGo "github.com/golang-jwt/jwt/v4"
 )
 
 func Protected() gin.HandlerFunc {
@@ -28,18 +28,38 @@ func Protected() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		claims := &handlers.AuthClaims{}
-		token, err := jwtGo.ParseWithClaims(tokenString, claims, func(token *jwtGo.Token) (any, error) {
-			return []byte(config.LoadConfig("JWT_SECRET")), nil
-		})
+		claims := &utils.AccessTokenClaims{}
+		if _, err := utils.ParseAccessToken(tokenString, claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired JWT"})
+			c.Abort()
+			return
+		}
 
-		if err != nil || !token.Valid {
+		// Deleted users and tokens issued before a password or role change are rejected
+		var user models.User
+		database.DB.Select("id", "token_version").First(&user, claims.Subject)
+		if user.ID == 0 || user.TokenVersion != claims.TokenVersion {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired JWT"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims.Issuer)
+		// Tokens signed out with /auth/logout
+		var revoked int64
+		if err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not verify JWT"})
+			c.Abort()
+			return
+		}
+		if revoked > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.Subject)
+		c.Set("token_id", claims.ID)
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
 		c.Next()
 	}
 }
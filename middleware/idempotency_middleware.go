@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"pos/config"
+	"pos/database"
+	"pos/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKeyTTL returns how long a stored response is replayed for.
+func idempotencyKeyTTL() time.Duration {
+	hours, err := strconv.Atoi(config.LoadConfig("IDEMPOTENCY_KEY_TTL_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// idempotencyLockTimeout returns how long a request holds its key before a
+// retry may take it over, in case the request never finished.
+func idempotencyLockTimeout() time.Duration {
+	seconds, err := strconv.Atoi(config.LoadConfig("IDEMPOTENCY_LOCK_TIMEOUT_SECONDS"))
+	if err != nil || seconds <= 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// responseRecorder keeps a copy of the response body written by the handler.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key header and body, and rejects reuse of a key with a
+// different request. Requests without the header run as usual. It must run
+// after Protected, since keys are scoped per user.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		userID, err := strconv.ParseUint(c.GetString("user_id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "User ID not found in context"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Could not read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.FullPath() + "\n"))
+		hash.Write(body)
+
+		now := time.Now()
+		record := models.IdempotencyKey{
+			UserID:      uint(userID),
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.FullPath(),
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			ExpiresAt:   now.Add(idempotencyKeyTTL()),
+			LockedUntil: now.Add(idempotencyLockTimeout()),
+		}
+
+		// Expired keys, and keys of requests that stopped without finishing, can be used again
+		if err := database.DB.Where("user_id = ? AND key = ?", record.UserID, key).
+			Scopes(staleIdempotencyKeys(now)).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+			c.Abort()
+			return
+		}
+
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+			c.Abort()
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			var existing models.IdempotencyKey
+			if err := database.DB.Where("user_id = ? AND key = ?", record.UserID, key).First(&existing).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+				c.Abort()
+				return
+			}
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				c.JSON(http.StatusConflict, gin.H{"message": "Idempotency-Key was already used for a different request"})
+			case !existing.Completed:
+				c.JSON(http.StatusConflict, gin.H{"message": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", []byte(existing.ResponseBody))
+			}
+			c.Abort()
+			return
+		}
+
+		// Only successful responses are stored. The key is released when the
+		// handler fails or panics, so that the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				if err := database.DB.Delete(&record).Error; err != nil {
+					log.Printf("idempotency key %d: could not release it: %v", record.ID, err)
+				}
+			}
+		}()
+
+		// Keep the key locked for as long as the handler runs, however long
+		// that takes, so that a retry cannot run the request a second time
+		stopLock := keepIdempotencyKeyLocked(record.ID)
+		defer stopLock()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		stopLock()
+
+		if recorder.Status() < http.StatusOK || recorder.Status() >= http.StatusMultipleChoices {
+			return
+		}
+		completed = true
+		if err := database.DB.Model(&record).Updates(map[string]any{
+			"completed":     true,
+			"status_code":   recorder.Status(),
+			"response_body": recorder.body.String(),
+		}).Error; err != nil {
+			// The request went through, so the key must not become free again:
+			// retries are turned away as in progress until it expires
+			log.Printf("idempotency key %d: could not store the response: %v", record.ID, err)
+			database.DB.Model(&record).Update("locked_until", record.ExpiresAt)
+		}
+	}
+}
+
+// keepIdempotencyKeyLocked pushes back the lock of an incomplete key at
+// intervals until the returned function is called. Calling it more than once
+// is fine.
+func keepIdempotencyKeyLocked(id uint) func() {
+	timeout := idempotencyLockTimeout()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(timeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := database.DB.Model(&models.IdempotencyKey{}).
+					Where("id = ? AND completed = ?", id, false).
+					Update("locked_until", time.Now().Add(timeout)).Error; err != nil {
+					log.Printf("idempotency key %d: could not extend its lock: %v", id, err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// staleIdempotencyKeys selects keys that can be used again: expired keys, and
+// keys of requests that stopped without finishing.
+func staleIdempotencyKeys(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(expires_at <= ? OR (completed = ? AND locked_until <= ?))", now, false, now)
+	}
+}
+
+// PruneIdempotencyKeys deletes the keys that can no longer be replayed.
+func PruneIdempotencyKeys() error {
+	return database.DB.Scopes(staleIdempotencyKeys(time.Now())).Delete(&models.IdempotencyKey{}).Error
+}
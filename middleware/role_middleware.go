@@ -4,12 +4,13 @@ import (
 	"net/http"
 	"pos/database"
 	"pos/models"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthorizeRole(requiredRole string) gin.HandlerFunc {
+func AuthorizeRole(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDStr := c.GetString("user_id")
 		if userIDStr == "" {
@@ -34,7 +35,7 @@ func AuthorizeRole(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
-		if user.Role != requiredRole {
+		if !slices.Contains(allowedRoles, user.Role) {
 			c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient permissions"})
 			c.Abort()
 			return
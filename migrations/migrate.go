@@ -9,13 +9,37 @@ import (
 func Migrate() {
 	database.DB.AutoMigrate(
 		&models.User{},
+		&models.Session{},
+		&models.RevokedToken{},
+		&models.PriceList{},
+		&models.PriceListItem{},
+		&models.Customer{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderTax{},
+		&models.OrderHistory{},
+		&models.OrderSequence{},
+		&models.TaxClass{},
 		&models.Category{},
 		&models.Product{},
 		&models.StockTransaction{},
 		&models.ProductPromotion{},
 		&models.CartPromotion{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.LoyaltyTransaction{},
+		&models.Payment{},
+		&models.PaymentIntent{},
+		&models.WebhookEvent{},
+		&models.Shift{},
+		&models.ShiftCashEvent{},
+		&models.ShiftTotal{},
+		&models.IdempotencyKey{},
 	)
+
+	// Refresh tokens moved to hashed sessions; drop the old plaintext column
+	if database.DB.Migrator().HasColumn(&models.User{}, "refresh_token") {
+		database.DB.Migrator().DropColumn(&models.User{}, "refresh_token")
+	}
 	fmt.Println("Database Migrated")
 }
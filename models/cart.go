@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Cart struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	UserID        uint           `gorm:"uniqueIndex" json:"user_id"`
+	User          User           `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	HoldExpiresAt *time.Time     `gorm:"index" json:"hold_expires_at,omitempty"` // When the stock held by the items is released unless the cart changes
+	Items         []CartItem     `gorm:"foreignKey:CartID" json:"items"`
+}
+
+type CartItem struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	CartID       uint           `gorm:"index" json:"cart_id"`
+	Cart         Cart           `gorm:"foreignKey:CartID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	ProductID    uint           `json:"product_id"`
+	Product      Product        `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Quantity     int            `json:"quantity"`
+	HeldQuantity int            `gorm:"default:0" json:"held_quantity"` // Units counted in Product.ReservedQuantity for this item
+}
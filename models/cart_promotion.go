@@ -12,8 +12,8 @@ type CartPromotion struct {
 	UpdatedAt             time.Time      `json:"updated_at"`
 	DeletedAt             gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
 	PromotionType         string         `json:"promotion_type"` // e.g., "percentage_discount", "fixed_discount"
-	DiscountValue         float64        `json:"discount_value"`
-	MinimumPurchaseAmount float64        `json:"minimum_purchase_amount"`
+	DiscountValue         Money          `json:"discount_value"`
+	MinimumPurchaseAmount Money          `json:"minimum_purchase_amount"`
 	StartDate             time.Time      `json:"start_date"`
 	EndDate               time.Time      `json:"end_date"`
 }
@@ -7,10 +7,12 @@ import (
 )
 
 type Category struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
-	Name      string         `gorm:"index" json:"name"`
-	Products  []Product      `gorm:"foreignKey:CategoryID" json:"-"`
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	Name       string         `gorm:"index" json:"name"`
+	TaxClassID *uint          `json:"tax_class_id"` // Default tax class for products in this category without their own
+	TaxClass   TaxClass       `gorm:"foreignKey:TaxClassID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	Products   []Product      `gorm:"foreignKey:CategoryID" json:"-"`
 }
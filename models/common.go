@@ -4,3 +4,15 @@ package models
 type MessageResponse struct {
 	Message string `json:"message"`
 }
+
+// FieldError describes a validation failure on a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse represents an API response carrying per-field validation errors.
+type ValidationErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
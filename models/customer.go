@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CustomerTierBronze = "bronze"
+	CustomerTierSilver = "silver"
+	CustomerTierGold   = "gold"
+)
+
+// Customer is a buyer known to the store. Unlike a User it has no login; it is
+// attached to orders placed at the counter.
+type Customer struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	Name        string         `gorm:"index" json:"name"`
+	Email       string         `gorm:"index" json:"email,omitempty"`
+	Phone       string         `gorm:"index" json:"phone,omitempty"`
+	Address     string         `json:"address,omitempty"`
+	Tier        string         `json:"tier,omitempty"`                       // Optional membership tier: bronze, silver or gold
+	PriceListID *uint          `gorm:"index" json:"price_list_id,omitempty"` // Price list the customer buys at; the default list applies when empty
+	PriceList   *PriceList     `gorm:"foreignKey:PriceListID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	Notes       string         `gorm:"type:text" json:"notes,omitempty"`
+}
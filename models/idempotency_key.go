@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the response to a request sent with an
+// Idempotency-Key header so that retries of it are answered without running
+// the request again.
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	LockedUntil  time.Time `json:"locked_until"` // An incomplete key is given up after this, so a crashed request can be retried
+	UserID       uint      `gorm:"uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	Key          string    `gorm:"uniqueIndex:idx_idempotency_keys_user_key;size:255" json:"key"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	RequestHash  string    `json:"request_hash"` // SHA-256 of the method, path and body
+	Completed    bool      `json:"completed"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody string    `gorm:"type:text" json:"response_body"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyTransactionType defines why a user's points balance changed
+type LoyaltyTransactionType string
+
+const (
+	LoyaltyTransactionEarn    LoyaltyTransactionType = "earn"    // Points earned on a completed order
+	LoyaltyTransactionRedeem  LoyaltyTransactionType = "redeem"  // Points spent as a discount at checkout
+	LoyaltyTransactionExpire  LoyaltyTransactionType = "expire"  // Unspent points that passed their expiry date
+	LoyaltyTransactionReverse LoyaltyTransactionType = "reverse" // Points taken back or returned when an order is cancelled or refunded
+)
+
+type LoyaltyTransaction struct {
+	ID              uint                   `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt         `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	UserID          uint                   `gorm:"index" json:"user_id"`
+	User            User                   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	OrderID         *uint                  `gorm:"index" json:"order_id,omitempty"`
+	Type            LoyaltyTransactionType `json:"type"`
+	Points          int                    `json:"points"`                            // Positive when points are added, negative when they are taken
+	RemainingPoints int                    `gorm:"default:0" json:"remaining_points"` // Unspent part of a positive entry, consumed oldest expiry first
+	ExpiresAt       *time.Time             `gorm:"index" json:"expires_at,omitempty"` // When the unspent part of a positive entry expires
+	Notes           string                 `json:"notes"`
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+
+	"pos/config"
+)
+
+// Money is an exact currency amount stored as a whole number of cents.
+// It is serialised as a JSON number with two decimals and stored in a
+// numeric(15,2) column, so amounts never pick up floating point drift.
+type Money int64
+
+const moneyScale = 100
+
+// RoundingMode decides how amounts that fall between two cents are rounded.
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"   // Ties round away from zero: 0.125 -> 0.13
+	RoundHalfEven RoundingMode = "half_even" // Ties round to the even cent (banker's rounding): 0.125 -> 0.12
+)
+
+var (
+	roundingOnce sync.Once
+	rounding     RoundingMode
+)
+
+// ConfiguredRounding returns the rounding mode set by MONEY_ROUNDING, half_up by default.
+func ConfiguredRounding() RoundingMode {
+	roundingOnce.Do(func() {
+		rounding = RoundHalfUp
+		if RoundingMode(config.LoadConfig("MONEY_ROUNDING")) == RoundHalfEven {
+			rounding = RoundHalfEven
+		}
+	})
+	return rounding
+}
+
+// MoneyFromUnits returns the amount of whole currency units, e.g. MoneyFromUnits(100) is 100.00.
+func MoneyFromUnits(units int64) Money {
+	return Money(units * moneyScale)
+}
+
+// decimalPattern matches plain decimal amounts, without exponents or fractions.
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// ParseMoney parses a decimal string such as "19.99". Values with more than
+// two decimals are rounded with the configured rounding mode.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	r.Mul(r, big.NewRat(moneyScale, 1))
+	cents := roundRat(r.Num(), r.Denom(), ConfiguredRounding())
+	if !cents.IsInt64() {
+		return 0, fmt.Errorf("money amount %q out of range", s)
+	}
+	return Money(cents.Int64()), nil
+}
+
+// MulInt returns the amount multiplied by n, saturated like MulRat.
+func (m Money) MulInt(n int) Money {
+	return saturate(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(n))))
+}
+
+// MulRat returns m * num / den rounded to the cent with the given mode. A
+// result that does not fit in a Money saturates at the largest or smallest
+// amount instead, so oversized percentages cannot crash a request.
+func (m Money) MulRat(num, den int64, mode RoundingMode) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num))
+	return saturate(roundRat(product, big.NewInt(den), mode))
+}
+
+// Percent returns pct percent of the amount, where pct is itself a
+// two-decimal amount (e.g. 12.50 for 12.5%), rounded with the configured mode.
+func (m Money) Percent(pct Money) Money {
+	return m.MulRat(int64(pct), 100*moneyScale, ConfiguredRounding())
+}
+
+// Min returns the smaller of the two amounts.
+func (m Money) Min(other Money) Money {
+	if other < m {
+		return other
+	}
+	return m
+}
+
+// Float64 returns the amount as a float, for ratios and display only.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/moneyScale, cents%moneyScale)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and numeric strings.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*m = 0
+		return nil
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (Money) GormDataType() string {
+	return "numeric(15,2)"
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case int64:
+		*m = MoneyFromUnits(v)
+		return nil
+	case float64:
+		parsed, err := ParseMoney(fmt.Sprintf("%.6f", v))
+		*m = parsed
+		return err
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		*m = parsed
+		return err
+	case string:
+		parsed, err := ParseMoney(v)
+		*m = parsed
+		return err
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+// saturate converts cents to a Money, clamping values out of range to the
+// largest amount or its negative, which can still be negated and printed.
+func saturate(cents *big.Int) Money {
+	switch {
+	case cents.IsInt64():
+		return Money(cents.Int64())
+	case cents.Sign() > 0:
+		return Money(math.MaxInt64)
+	default:
+		return Money(-math.MaxInt64)
+	}
+}
+
+// roundRat divides num by den and rounds the quotient to an integer.
+func roundRat(num, den *big.Int, mode RoundingMode) *big.Int {
+	if den.Sign() < 0 {
+		num = new(big.Int).Neg(num)
+		den = new(big.Int).Neg(den)
+	}
+	negative := num.Sign() < 0
+	absNum := new(big.Int).Abs(num)
+
+	quotient, remainder := new(big.Int).QuoRem(absNum, den, new(big.Int))
+	twiceRemainder := new(big.Int).Mul(remainder, big.NewInt(2))
+
+	switch twiceRemainder.Cmp(den) {
+	case 1:
+		quotient.Add(quotient, big.NewInt(1))
+	case 0:
+		if mode != RoundHalfEven || quotient.Bit(0) == 1 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if negative {
+		quotient.Neg(quotient)
+	}
+	return quotient
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		mode     RoundingMode
+		want     int64
+	}{
+		{5, 10, RoundHalfUp, 1},
+		{15, 10, RoundHalfUp, 2},
+		{25, 10, RoundHalfUp, 3},
+		{-5, 10, RoundHalfUp, -1},
+		{-25, 10, RoundHalfUp, -3},
+		{5, 10, RoundHalfEven, 0},
+		{15, 10, RoundHalfEven, 2},
+		{25, 10, RoundHalfEven, 2},
+		{-5, 10, RoundHalfEven, 0},
+		{-15, 10, RoundHalfEven, -2},
+		{-25, 10, RoundHalfEven, -2},
+		{49, 100, RoundHalfUp, 0},
+		{51, 100, RoundHalfEven, 1},
+		{-51, 100, RoundHalfEven, -1},
+		{5, -10, RoundHalfUp, -1},
+		{-25, -10, RoundHalfEven, 2},
+		{0, 7, RoundHalfUp, 0},
+	}
+	for _, tt := range tests {
+		got := roundRat(big.NewInt(tt.num), big.NewInt(tt.den), tt.mode)
+		if got.Int64() != tt.want {
+			t.Errorf("roundRat(%d, %d, %s) = %s, want %d", tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"19.99", 1999, false},
+		{" 7 ", 700, false},
+		{"0.125", 13, false},
+		{"0.135", 14, false},
+		{"0.124", 12, false},
+		{"-0.125", -13, false},
+		{"-0.005", -1, false},
+		{"-19.99", -1999, false},
+		{"1/8", 0, true},
+		{"1e3", 0, true},
+		{"0x10", 0, true},
+		{"1.", 0, true},
+		{".5", 0, true},
+		{"+5", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+		{"92233720368547758.08", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		m        Money
+		num, den int64
+		mode     RoundingMode
+		want     Money
+	}{
+		{1000, 1, 3, RoundHalfUp, 333},
+		{1000, 2, 3, RoundHalfUp, 667},
+		{25, 1, 2, RoundHalfUp, 13},
+		{25, 1, 2, RoundHalfEven, 12},
+		{-25, 1, 2, RoundHalfUp, -13},
+		{-25, 1, 2, RoundHalfEven, -12},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulRat(tt.num, tt.den, tt.mode); got != tt.want {
+			t.Errorf("%s.MulRat(%d, %d, %s) = %s, want %s", tt.m, tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMulOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want Money
+	}{
+		{"MulRat max", Money(math.MaxInt64).MulRat(2, 1, RoundHalfUp), math.MaxInt64},
+		{"MulRat min", Money(math.MinInt64).MulRat(2, 1, RoundHalfUp), -math.MaxInt64},
+		{"MulRat negative ratio", Money(math.MaxInt64).MulRat(-2, 1, RoundHalfUp), -math.MaxInt64},
+		{"Percent", Money(math.MaxInt64).Percent(MoneyFromUnits(200)), math.MaxInt64},
+		{"MulInt max", Money(math.MaxInt64).MulInt(2), math.MaxInt64},
+		{"MulInt min", Money(math.MaxInt64).MulInt(-2), -math.MaxInt64},
+		{"MulInt in range", Money(1999).MulInt(3), 5997},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -6,18 +6,41 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
 type Order struct {
 	ID                uint           `gorm:"primarykey" json:"id"`
+	InvoiceNumber     *string        `gorm:"uniqueIndex;size:100" json:"invoice_number,omitempty"` // InvoiceNumber adalah nomor faktur yang mudah dibaca, misal INV/2026/10/000123
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	GrossTotal        float64        `json:"gross_total"`                          // GrossTotal adalah total harga dari semua item sebelum diskon per item diterapkan
-	SubTotal          float64        `json:"sub_total"`                            // SubTotal adalah total harga dari semua item setelah diskon per item diterapkan
-	ItemDiscountTotal float64        `gorm:"default:0" json:"item_discount_total"` // ItemDiscountTotal adalah total akumulasi diskon yang diberikan per item
-	CartDiscount      float64        `gorm:"default:0" json:"cart_discount"`       // CartDiscount adalah diskon yang diterapkan pada total belanja (misal: diskon minimal, kupon)
-	TotalAmount       float64        `json:"total_amount"`                         // TotalAmount adalah jumlah akhir yang harus dibayar pelanggan (SubTotal - CartDiscount)
-	PaymentMethod     string         `json:"payment_method"`
-	UserID            uint           `json:"user_id"`
-	User              User           `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	GrossTotal        Money          `json:"gross_total"`                              // GrossTotal adalah total harga dari semua item sebelum diskon per item diterapkan
+	SubTotal          Money          `json:"sub_total"`                                // SubTotal adalah total harga dari semua item setelah diskon per item diterapkan
+	ItemDiscountTotal Money          `gorm:"default:0" json:"item_discount_total"`     // ItemDiscountTotal adalah total akumulasi diskon yang diberikan per item
+	CartDiscount      Money          `gorm:"default:0" json:"cart_discount"`           // CartDiscount adalah diskon yang diterapkan pada total belanja (misal: diskon minimal, kupon)
+	CartPromotionID   *uint          `gorm:"index" json:"cart_promotion_id,omitempty"` // CartPromotionID adalah promosi keranjang yang menghasilkan CartDiscount
+	PriceListID       *uint          `gorm:"index" json:"price_list_id,omitempty"`     // PriceListID adalah daftar harga yang dipakai untuk harga item
+	TaxTotal          Money          `gorm:"default:0" json:"tax_total"`               // TaxTotal adalah total pajak; pajak eksklusif ditambahkan ke TotalAmount, pajak inklusif sudah termasuk dalam harga
+	PointsRedeemed    int            `gorm:"default:0" json:"points_redeemed"`         // PointsRedeemed adalah jumlah poin loyalitas yang ditukarkan pada pesanan ini
+	PointsDiscount    Money          `gorm:"default:0" json:"points_discount"`         // PointsDiscount adalah potongan dari penukaran poin loyalitas
+	PointsEarned      int            `gorm:"default:0" json:"points_earned"`           // PointsEarned adalah poin loyalitas yang didapat dari pesanan ini
+	TotalAmount       Money          `json:"total_amount"`                             // TotalAmount adalah jumlah akhir yang harus dibayar pelanggan (SubTotal - CartDiscount + pajak eksklusif - PointsDiscount)
+	Status            string         `gorm:"default:'completed';index" json:"status"`
+	PaymentMethod     string         `json:"payment_method"` // PaymentMethod adalah metode pembayaran, atau "split" jika dibayar dengan beberapa metode
+	PaymentStatus     string         `gorm:"default:'paid';index" json:"payment_status"`
+	UserID            *uint          `gorm:"index" json:"user_id"` // UserID adalah akun pembeli; kosong untuk penjualan kasir ke pelanggan toko tanpa akun
+	User              *User          `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	ShiftID           *uint          `gorm:"index" json:"shift_id,omitempty"`    // ShiftID adalah shift kasir tempat pesanan dibuat
+	CashierID         *uint          `gorm:"index" json:"cashier_id,omitempty"`  // CashierID adalah pengguna yang membuat pesanan di kasir; kosong untuk checkout keranjang
+	CustomerID        *uint          `gorm:"index" json:"customer_id,omitempty"` // CustomerID adalah pelanggan toko yang membeli; kosong untuk pembeli tanpa data
+	Customer          *Customer      `gorm:"foreignKey:CustomerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"customer,omitempty"`
 	OrderItems        []OrderItem    `gorm:"foreignKey:OrderID" json:"order_items"`
+	Taxes             []OrderTax     `gorm:"foreignKey:OrderID" json:"taxes,omitempty"`
+	Payments          []Payment      `gorm:"foreignKey:OrderID" json:"payments,omitempty"`
+	History           []OrderHistory `gorm:"foreignKey:OrderID" json:"history,omitempty"`
 }
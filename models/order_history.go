@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OrderHistoryItemAdded      = "item_added"
+	OrderHistoryItemRemoved    = "item_removed"
+	OrderHistoryQuantityChange = "quantity_changed"
+)
+
+// OrderHistory is one change made to the items of an order after it was placed.
+type OrderHistory struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	OrderID     uint      `gorm:"index" json:"order_id"`
+	Order       Order     `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	UserID      uint      `json:"user_id"` // User who made the change
+	Action      string    `json:"action"`
+	ProductID   uint      `json:"product_id"`
+	OldQuantity int       `json:"old_quantity"`
+	NewQuantity int       `json:"new_quantity"`
+	OldTotal    Money     `json:"old_total"` // Order total before the edit
+	NewTotal    Money     `json:"new_total"` // Order total after the edit
+}
@@ -7,17 +7,24 @@ import (
 )
 
 type OrderItem struct {
-	ID                uint           `gorm:"primarykey" json:"id"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	Quantity          int            `json:"quantity"`
-	Price             float64        `json:"price"` // Original price of the product at the time of sale
-	DiscountedPrice   float64        `json:"discounted_price"` // Price after item-specific discount
-	ItemDiscount      float64        `gorm:"default:0" json:"item_discount"` // Discount amount for this item
-	IsFreeItem        bool           `gorm:"default:false" json:"is_free_item"`
-	OrderID           uint           `json:"order_id"`
-	Order             Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
-	ProductID         uint           `json:"product_id"`
-	Product           Product        `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	Quantity           int            `json:"quantity"`
+	Price              Money          `json:"price"`                          // Original price of the product at the time of sale
+	DiscountedPrice    Money          `json:"discounted_price"`               // Price after item-specific discount
+	ItemDiscount       Money          `gorm:"default:0" json:"item_discount"` // Discount amount for this item
+	IsFreeItem         bool           `gorm:"default:false" json:"is_free_item"`
+	ProductPromotionID *uint          `gorm:"index" json:"product_promotion_id,omitempty"` // Promotion that discounted this item or granted it for free
+	CartDiscountShare  Money          `gorm:"default:0" json:"cart_discount_share"`        // Part of the order's cart discount allocated to this item
+	TaxClassID         *uint          `json:"tax_class_id,omitempty"`
+	TaxRate            Money          `gorm:"default:0" json:"tax_rate"`
+	TaxInclusive       bool           `gorm:"default:false" json:"tax_inclusive"`
+	TaxableAmount      Money          `gorm:"default:0" json:"taxable_amount"` // Amount the tax was charged on, after item and cart discounts and excluding the tax itself
+	TaxAmount          Money          `gorm:"default:0" json:"tax_amount"`
+	OrderID            uint           `json:"order_id"`
+	Order              Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	ProductID          uint           `json:"product_id"`
+	Product            Product        `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
 }
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderSequence holds the last invoice number issued in a numbering scope,
+// such as a month or a shift within a day.
+type OrderSequence struct {
+	Scope      string    `gorm:"primaryKey;size:100" json:"scope"`
+	LastNumber int64     `json:"last_number"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderTax is the per-order tax breakdown, one row per tax class and rate charged on the order.
+type OrderTax struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	OrderID       uint           `gorm:"index" json:"order_id"`
+	Order         Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	TaxClassID    *uint          `gorm:"index" json:"tax_class_id"`
+	Name          string         `json:"name"`
+	Rate          Money          `json:"rate"`
+	Inclusive     bool           `json:"inclusive"`
+	TaxableAmount Money          `json:"taxable_amount"` // Amount the tax was charged on, excluding the tax itself
+	TaxAmount     Money          `json:"tax_amount"`
+}
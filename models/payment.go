@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentMethodCash    = "cash"
+	PaymentMethodCard    = "card"
+	PaymentMethodVoucher = "voucher"
+	PaymentMethodEWallet = "e_wallet"
+)
+
+const (
+	PaymentStatusUnpaid        = "unpaid"
+	PaymentStatusPartiallyPaid = "partially_paid"
+	PaymentStatusPaid          = "paid"
+	PaymentStatusRefunded      = "refunded"
+)
+
+// Payment is one tender applied to an order. An order can be settled with several payments.
+type Payment struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	OrderID   uint           `gorm:"index" json:"order_id"`
+	Order     Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Method    string         `json:"method"`                          // e.g., "cash", "card", "voucher", "e_wallet"
+	Amount    Money          `json:"amount"`                          // Amount applied to the order
+	Tendered  Money          `json:"tendered"`                        // Amount handed over by the customer; only differs from Amount for cash
+	Change    Money          `json:"change"`                          // Tendered - Amount, returned to the customer
+	Reference string         `json:"reference"`                       // Card approval code, voucher code, e-wallet transaction ID
+	UserID    uint           `json:"user_id"`                         // User who took the payment
+	ShiftID   *uint          `gorm:"index" json:"shift_id,omitempty"` // Shift of the user who took the payment, if one was open
+	User      User           `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentIntentRequiresCapture = "requires_capture" // Authorized by the customer, waiting to be captured
+	PaymentIntentSucceeded       = "succeeded"        // Captured; a Payment has been recorded on the order
+	PaymentIntentFailed          = "failed"
+	PaymentIntentRefundPending   = "refund_pending" // The order was closed; the captured amount still has to be refunded with the provider
+	PaymentIntentRefunded        = "refunded"       // Fully refunded
+)
+
+// PaymentIntent tracks a card or e-wallet payment collected through a payment gateway.
+type PaymentIntent struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	OrderID          uint           `gorm:"index" json:"order_id"`
+	Order            Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Provider         string         `gorm:"uniqueIndex:idx_payment_intents_provider_intent" json:"provider"`
+	ProviderIntentID string         `gorm:"uniqueIndex:idx_payment_intents_provider_intent" json:"provider_intent_id"`
+	Method           string         `json:"method"` // "card" or "e_wallet"
+	Amount           Money          `json:"amount"`
+	RefundedAmount   Money          `gorm:"default:0" json:"refunded_amount"`
+	Status           string         `gorm:"index" json:"status"`
+	ClientSecret     string         `gorm:"-" json:"client_secret,omitempty"` // Only returned when the intent is created
+	PaymentID        *uint          `json:"payment_id,omitempty"`             // Payment recorded on the order once the intent succeeded
+	UserID           uint           `json:"user_id"`                          // User who started the payment
+	RefundAttemptAt  *time.Time     `json:"refund_attempt_at,omitempty"`      // Last time a pending refund was sent to the provider
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceList is a set of prices, such as retail, wholesale or member prices,
+// that replaces the list price of products for the customers assigned to it.
+// Products without an item on the list get DiscountPercent off their list price.
+type PriceList struct {
+	ID              uint            `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	Name            string          `gorm:"index" json:"name"`
+	Description     string          `json:"description,omitempty"`
+	DiscountPercent Money           `gorm:"default:0" json:"discount_percent"` // Percent off the list price of products not on the list, e.g. 5.00
+	IsDefault       bool            `gorm:"default:false" json:"is_default"`   // Used for orders and listings without a customer price list
+	Active          bool            `json:"active"`
+	Items           []PriceListItem `gorm:"foreignKey:PriceListID" json:"items"`
+}
+
+// PriceListItem prices a product on a price list from MinQuantity units up,
+// either at a fixed unit Price or at DiscountPercent off the list price.
+// Several items for one product give quantity breaks.
+type PriceListItem struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	PriceListID     uint      `gorm:"index" json:"price_list_id"`
+	PriceList       PriceList `gorm:"foreignKey:PriceListID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	ProductID       uint      `gorm:"index" json:"product_id"`
+	Product         Product   `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	MinQuantity     int       `gorm:"default:1" json:"min_quantity"`
+	Price           *Money    `json:"price,omitempty"`            // Fixed unit price
+	DiscountPercent *Money    `json:"discount_percent,omitempty"` // Percent off the list price when Price is not set
+}
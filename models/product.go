@@ -13,9 +13,11 @@ type Product struct {
 	DeletedAt        gorm.DeletedAt     `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
 	Name             string             `json:"name"`
 	SKU              string             `gorm:"index" json:"sku"`
-	Price            float64            `json:"price"`
+	Price            Money              `json:"price"`
 	CategoryID       *uint              `json:"category_id"`
 	Category         Category           `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	TaxClassID       *uint              `json:"tax_class_id"`
+	TaxClass         TaxClass           `gorm:"foreignKey:TaxClassID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
 	Promotions       []ProductPromotion `gorm:"foreignKey:ProductID" json:"promotions,omitempty"`
 	Quantity         int                `json:"quantity"`
 	ReservedQuantity int                `json:"reserved_quantity"`
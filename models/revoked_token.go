@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken is an access token that was signed out before it expired. It
+// is kept until ExpiresAt, after which the token is rejected anyway.
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	JTI       string    `gorm:"uniqueIndex;size:64" json:"jti"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
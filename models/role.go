@@ -1,6 +1,7 @@
 package models
 
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleCashier = "cashier"
 )
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one refresh token issued to a device. Refreshing rotates the
+// token: a new session in the same family replaces the old one, so every
+// device keeps a single live session per login. Only a hash of the token is
+// stored.
+type Session struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	User         User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	FamilyID     string     `gorm:"index;size:64" json:"family_id"` // Shared by all sessions rotated from the same login
+	TokenHash    string     `gorm:"uniqueIndex;size:64" json:"-"`   // SHA-256 of the refresh token
+	DeviceName   string     `json:"device_name,omitempty"`
+	UserAgent    string     `json:"user_agent,omitempty"`
+	IPAddress    string     `json:"ip_address,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RotatedAt    *time.Time `json:"rotated_at,omitempty"` // Set once the token was exchanged for a new one
+	ReplacedByID *uint      `json:"replaced_by_id,omitempty"`
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
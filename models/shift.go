@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+)
+
+const (
+	ShiftCashIn  = "cash_in"  // Cash put into the drawer, e.g. extra change
+	ShiftCashOut = "cash_out" // Cash taken out of the drawer, e.g. a bank drop or petty cash
+)
+
+// Shift is a cashier's till session, from opening the drawer with a float
+// until the drawer is counted and the shift is closed.
+type Shift struct {
+	ID             uint             `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt   `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	UserID         uint             `gorm:"index;uniqueIndex:idx_shifts_open_user,where:status = 'open'" json:"user_id"` // A user can only have one open shift
+	User           User             `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Status         string           `gorm:"default:'open';index" json:"status"`
+	OpenedAt       time.Time        `json:"opened_at"`
+	ClosedAt       *time.Time       `json:"closed_at,omitempty"`
+	OpeningFloat   Money            `json:"opening_float"`                    // Cash in the drawer when the shift opened
+	ExpectedCash   Money            `gorm:"default:0" json:"expected_cash"`   // Float + cash sales + cash in - cash out, set on close
+	CountedCash    *Money           `json:"counted_cash,omitempty"`           // Cash counted in the drawer on close
+	CashDifference Money            `gorm:"default:0" json:"cash_difference"` // CountedCash - ExpectedCash; negative when the drawer is short
+	OrderCount     int64            `gorm:"default:0" json:"order_count"`     // Orders placed during the shift and not cancelled, set on close
+	Notes          string           `json:"notes"`
+	CashEvents     []ShiftCashEvent `gorm:"foreignKey:ShiftID" json:"cash_events,omitempty"`
+	Totals         []ShiftTotal     `gorm:"foreignKey:ShiftID" json:"totals,omitempty"`
+}
+
+// ShiftCashEvent is cash put into or taken out of the drawer outside of a sale.
+type ShiftCashEvent struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	ShiftID   uint           `gorm:"index" json:"shift_id"`
+	Shift     Shift          `gorm:"foreignKey:ShiftID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Type      string         `json:"type"`   // "cash_in" or "cash_out"
+	Amount    Money          `json:"amount"` // Always positive
+	Reason    string         `json:"reason"`
+	OrderID   *uint          `gorm:"index" json:"order_id,omitempty"` // Order whose cash payment was handed back, for refunds
+	UserID    uint           `json:"user_id"`
+}
+
+// ShiftTotal is the expected and counted amount of one payment method when the shift closed.
+type ShiftTotal struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	ShiftID    uint           `gorm:"index" json:"shift_id"`
+	Shift      Shift          `gorm:"foreignKey:ShiftID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Method     string         `json:"method"`
+	Payments   int64          `json:"payments"`   // Number of payments taken with the method
+	Sales      Money          `json:"sales"`      // Amount taken with the method
+	Expected   Money          `json:"expected"`   // For cash this includes the float and cash in/out events
+	Counted    Money          `json:"counted"`    // Counted in the drawer, or the settlement amount for other methods
+	Difference Money          `json:"difference"` // Counted - Expected
+}
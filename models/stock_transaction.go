@@ -39,7 +39,7 @@ type StockTransaction struct {
 	CreatedAt time.Time               `json:"created_at"`
 	UpdatedAt time.Time               `json:"updated_at"`
 	DeletedAt gorm.DeletedAt          `gorm:"index" json:"deleted_at,omitempty"`
-		ProductID uint                    `json:"product_id"`
+	ProductID uint                    `json:"product_id"`
 	Product   Product                 `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
 	UserID    uint                    `json:"user_id"`
 	User      User                    `json:"user"`
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TaxClass struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string"`
+	Name      string         `gorm:"index" json:"name"`
+	Rate      Money          `json:"rate"`                           // Tax rate in percent, e.g. 11.00
+	Inclusive bool           `gorm:"default:false" json:"inclusive"` // Inclusive prices already contain the tax; exclusive prices have it added on top
+}
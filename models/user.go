@@ -14,6 +14,6 @@ type User struct {
 	Username     string         `gorm:"unique" json:"username"`
 	Password     string         `json:"-"`
 	Name         string         `json:"name"`
-	RefreshToken string         `json:"-"`
 	Role         string         `gorm:"default:'user'" json:"role"`
+	TokenVersion int            `gorm:"default:0" json:"-"` // Bumped on password or role changes to invalidate issued access tokens
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEvent records a processed payment gateway webhook so that
+// redelivered events are only applied once.
+type WebhookEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `gorm:"uniqueIndex:idx_webhook_events_provider_event" json:"provider"`
+	EventID   string    `gorm:"uniqueIndex:idx_webhook_events_provider_event" json:"event_id"`
+	Type      string    `json:"type"`
+	Payload   string    `gorm:"type:text" json:"payload"`
+}
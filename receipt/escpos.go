@@ -0,0 +1,41 @@
+package receipt
+
+import "bytes"
+
+// ESC/POS command sequences.
+var (
+	escInit        = []byte{0x1b, 0x40}             // ESC @: reset the printer
+	escAlignLeft   = []byte{0x1b, 0x61, 0x00}       // ESC a 0
+	escAlignCenter = []byte{0x1b, 0x61, 0x01}       // ESC a 1
+	escBoldOn      = []byte{0x1b, 0x45, 0x01}       // ESC E 1
+	escBoldOff     = []byte{0x1b, 0x45, 0x00}       // ESC E 0
+	escFeed        = []byte{0x1b, 0x64, 0x04}       // ESC d 4: feed four lines past the cutter
+	gsPartialCut   = []byte{0x1d, 0x56, 0x42, 0x00} // GS V 66 0: feed and partial cut
+)
+
+// ESCPOS renders the receipt as ESC/POS commands for thermal receipt printers.
+func (r Receipt) ESCPOS() []byte {
+	var b bytes.Buffer
+	b.Write(escInit)
+
+	for _, line := range r.Lines {
+		if line.Align == AlignCenter {
+			b.Write(escAlignCenter)
+		}
+		if line.Bold {
+			b.Write(escBoldOn)
+		}
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+		if line.Bold {
+			b.Write(escBoldOff)
+		}
+		if line.Align == AlignCenter {
+			b.Write(escAlignLeft)
+		}
+	}
+
+	b.Write(escFeed)
+	b.Write(gsPartialCut)
+	return b.Bytes()
+}
@@ -0,0 +1,64 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfFontSize  = 8.0
+	pdfLeading   = 10.0
+	pdfMargin    = 12.0
+	pdfCharWidth = 0.6 // Width of a Courier character relative to the font size
+)
+
+// PDF renders the receipt as a single-page PDF sized like a paper receipt.
+// It uses the standard Courier fonts, so no fonts are embedded.
+func (r Receipt) PDF() []byte {
+	width := float64(r.Width)*pdfFontSize*pdfCharWidth + 2*pdfMargin
+	height := float64(len(r.Lines))*pdfLeading + 2*pdfMargin
+
+	var content bytes.Buffer
+	content.WriteString("BT\n")
+	fmt.Fprintf(&content, "%.2f TL\n", pdfLeading)
+	fmt.Fprintf(&content, "%.2f %.2f Td\n", pdfMargin, height-pdfMargin-pdfFontSize)
+	for _, line := range r.Lines {
+		font := "F1"
+		if line.Bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "/%s %.1f Tf (%s) Tj T*\n", font, pdfFontSize, pdfEscape(r.padded(line)))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfEscape escapes the characters that are special inside a PDF string.
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
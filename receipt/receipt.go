@@ -0,0 +1,211 @@
+// Package receipt lays out order receipts and renders them as plain text,
+// ESC/POS printer commands or PDF.
+package receipt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pos/config"
+	"pos/models"
+)
+
+// defaultWidth is the number of characters per line, which fits 80mm thermal paper.
+const defaultWidth = 42
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+)
+
+// Line is one printed line of a receipt.
+type Line struct {
+	Text  string
+	Bold  bool
+	Align Align
+}
+
+// Receipt is the laid out receipt of an order.
+type Receipt struct {
+	OrderID uint
+	Width   int
+	Lines   []Line
+}
+
+// Width returns the characters per line set by RECEIPT_WIDTH, 42 by default.
+func Width() int {
+	width, err := strconv.Atoi(config.LoadConfig("RECEIPT_WIDTH"))
+	if err != nil || width < 24 {
+		return defaultWidth
+	}
+	return width
+}
+
+// FromOrder lays out the receipt of an order. The order must have its
+// OrderItems.Product, Taxes and Payments loaded.
+func FromOrder(order models.Order) Receipt {
+	r := Receipt{OrderID: order.ID, Width: Width()}
+
+	// Store header
+	if name := config.LoadConfig("STORE_NAME"); name != "" {
+		r.add(Line{Text: name, Bold: true, Align: AlignCenter})
+	}
+	for _, key := range []string{"STORE_ADDRESS", "STORE_PHONE"} {
+		if value := config.LoadConfig(key); value != "" {
+			r.add(Line{Text: value, Align: AlignCenter})
+		}
+	}
+	r.separator()
+	r.columns(fmt.Sprintf("Order #%d", order.ID), order.CreatedAt.Format("2006-01-02 15:04"))
+	if order.InvoiceNumber != nil {
+		r.add(Line{Text: "Invoice " + *order.InvoiceNumber})
+	}
+	if order.Status != models.OrderStatusCompleted {
+		r.add(Line{Text: strings.ToUpper(order.Status), Bold: true, Align: AlignCenter})
+	}
+	r.separator()
+
+	// Line items
+	for _, item := range order.OrderItems {
+		name := item.Product.Name
+		if name == "" {
+			name = fmt.Sprintf("Product #%d", item.ProductID)
+		}
+		if item.IsFreeItem {
+			r.add(Line{Text: "FREE " + name})
+			r.columns(fmt.Sprintf("  %d x %s", item.Quantity, item.Price), "0.00")
+			continue
+		}
+		r.add(Line{Text: name})
+		r.columns(fmt.Sprintf("  %d x %s", item.Quantity, item.Price), item.Price.MulInt(item.Quantity).String())
+		if item.ItemDiscount > 0 {
+			r.columns("  Discount", "-"+item.ItemDiscount.String())
+		}
+	}
+	r.separator()
+
+	// Totals
+	if order.ItemDiscountTotal > 0 {
+		r.columns("Gross total", order.GrossTotal.String())
+		r.columns("Item discounts", "-"+order.ItemDiscountTotal.String())
+	}
+	r.columns("Subtotal", order.SubTotal.String())
+	if order.CartDiscount > 0 {
+		r.columns("Cart discount", "-"+order.CartDiscount.String())
+	}
+	for _, tax := range order.Taxes {
+		label := fmt.Sprintf("%s %s%%", tax.Name, tax.Rate)
+		if tax.Inclusive {
+			label += " (incl.)"
+		}
+		r.columns(label, tax.TaxAmount.String())
+	}
+	if order.PointsDiscount > 0 {
+		r.columns(fmt.Sprintf("Points (%d)", order.PointsRedeemed), "-"+order.PointsDiscount.String())
+	}
+	r.add(r.columnLine("TOTAL", order.TotalAmount.String(), true))
+	r.separator()
+
+	// Payments
+	for _, payment := range order.Payments {
+		label := paymentLabel(payment.Method)
+		if payment.Reference != "" && payment.Method != models.PaymentMethodCash {
+			label += " " + payment.Reference
+		}
+		r.columns(label, payment.Amount.String())
+		if payment.Change > 0 {
+			r.columns("  Tendered", payment.Tendered.String())
+			r.columns("  Change", payment.Change.String())
+		}
+	}
+	if len(order.Payments) == 0 && order.PaymentMethod != "" {
+		r.columns(paymentLabel(order.PaymentMethod), order.TotalAmount.String())
+	}
+	if order.PaymentStatus != "" && order.PaymentStatus != models.PaymentStatusPaid {
+		r.columns("Payment status", order.PaymentStatus)
+	}
+	if order.PointsEarned > 0 {
+		r.columns("Points earned", strconv.Itoa(order.PointsEarned))
+	}
+
+	if footer := config.LoadConfig("RECEIPT_FOOTER"); footer != "" {
+		r.separator()
+		r.add(Line{Text: footer, Align: AlignCenter})
+	}
+	return r
+}
+
+func paymentLabel(method string) string {
+	switch method {
+	case models.PaymentMethodCash:
+		return "Cash"
+	case models.PaymentMethodCard:
+		return "Card"
+	case models.PaymentMethodVoucher:
+		return "Voucher"
+	case models.PaymentMethodEWallet:
+		return "E-wallet"
+	default:
+		return method
+	}
+}
+
+// add appends a line, wrapping text longer than the receipt width.
+func (r *Receipt) add(line Line) {
+	text := printable(line.Text)
+	for len(text) > r.Width {
+		cut := strings.LastIndex(text[:r.Width], " ")
+		if cut <= 0 {
+			cut = r.Width
+		}
+		r.Lines = append(r.Lines, Line{Text: strings.TrimRight(text[:cut], " "), Bold: line.Bold, Align: line.Align})
+		text = strings.TrimLeft(text[cut:], " ")
+	}
+	line.Text = text
+	r.Lines = append(r.Lines, line)
+}
+
+func (r *Receipt) separator() {
+	r.Lines = append(r.Lines, Line{Text: strings.Repeat("-", r.Width)})
+}
+
+func (r *Receipt) columns(left, right string) {
+	r.add(r.columnLine(left, right, false))
+}
+
+// columnLine puts left and right on one line, cutting left short if both do not fit.
+func (r *Receipt) columnLine(left, right string, bold bool) Line {
+	left, right = printable(left), printable(right)
+	space := r.Width - len(right) - 1
+	if space < 0 {
+		space = 0
+	}
+	if len(left) > space {
+		left = left[:space]
+	}
+	return Line{Text: left + strings.Repeat(" ", r.Width-len(left)-len(right)) + right, Bold: bold}
+}
+
+// printable replaces characters that thermal printers and the standard PDF
+// fonts cannot show, so every character is one byte wide.
+func printable(s string) string {
+	var b strings.Builder
+	for _, ch := range s {
+		if ch < 0x20 || ch > 0x7e {
+			ch = '?'
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// padded returns the text of a line aligned within the receipt width.
+func (r Receipt) padded(line Line) string {
+	if line.Align == AlignCenter && len(line.Text) < r.Width {
+		return strings.Repeat(" ", (r.Width-len(line.Text))/2) + line.Text
+	}
+	return line.Text
+}
@@ -0,0 +1,256 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pos/models"
+)
+
+func TestAddWrapsLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Line
+		want []Line
+	}{
+		{"fits", Line{Text: "Coffee"}, []Line{{Text: "Coffee"}}},
+		{"exact width", Line{Text: "0123456789"}, []Line{{Text: "0123456789"}}},
+		{"wraps at a space", Line{Text: "Iced Coffee Latte"}, []Line{{Text: "Iced"}, {Text: "Coffee"}, {Text: "Latte"}}},
+		{"cuts long words", Line{Text: "Cappuccinos"}, []Line{{Text: "Cappuccino"}, {Text: "s"}}},
+		{"keeps style", Line{Text: "Grand Total Amount", Bold: true, Align: AlignCenter}, []Line{
+			{Text: "Grand", Bold: true, Align: AlignCenter},
+			{Text: "Total", Bold: true, Align: AlignCenter},
+			{Text: "Amount", Bold: true, Align: AlignCenter},
+		}},
+		{"replaces unprintable", Line{Text: "Café\tau lait"}, []Line{{Text: "Caf??au"}, {Text: "lait"}}},
+	}
+	for _, tt := range tests {
+		r := Receipt{Width: 10}
+		r.add(tt.in)
+		if fmt.Sprint(r.Lines) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: add(%q) = %v, want %v", tt.name, tt.in.Text, r.Lines, tt.want)
+		}
+	}
+}
+
+func TestColumnLine(t *testing.T) {
+	tests := []struct {
+		left, right string
+		want        string
+	}{
+		{"Cash", "10.00", "Cash           10.00"},
+		{"Subtotal", "1234567.00", "Subtotal  1234567.00"},
+		{"Card APPROVAL-1234", "99.00", "Card APPROVAL- 99.00"},
+		{"", "0.00", "                0.00"},
+	}
+	for _, tt := range tests {
+		r := Receipt{Width: 20}
+		if got := r.columnLine(tt.left, tt.right, false).Text; got != tt.want {
+			t.Errorf("columnLine(%q, %q) = %q, want %q", tt.left, tt.right, got, tt.want)
+		}
+	}
+}
+
+func testOrder() models.Order {
+	invoice := "INV/2026/10/000007"
+	return models.Order{
+		ID:                7,
+		InvoiceNumber:     &invoice,
+		CreatedAt:         time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC),
+		GrossTotal:        10000,
+		ItemDiscountTotal: 1000,
+		SubTotal:          9000,
+		PointsRedeemed:    5,
+		PointsDiscount:    500,
+		PointsEarned:      85,
+		TotalAmount:       8500,
+		Status:            models.OrderStatusCompleted,
+		PaymentMethod:     "split",
+		PaymentStatus:     models.PaymentStatusPaid,
+		OrderItems: []models.OrderItem{
+			{Quantity: 2, Price: 5000, ItemDiscount: 1000, Product: models.Product{Name: "Extra Large Premium Arabica Coffee Beans"}},
+			{Quantity: 1, Price: 1500, IsFreeItem: true, Product: models.Product{Name: "Mug"}},
+		},
+		Taxes: []models.OrderTax{
+			{Name: "VAT", Rate: 1100, Inclusive: true, TaxAmount: 892},
+		},
+		Payments: []models.Payment{
+			{Method: models.PaymentMethodCash, Amount: 5000, Tendered: 10000, Change: 5000},
+			{Method: models.PaymentMethodCard, Amount: 3500, Reference: "APPROVAL-123456789012345678"},
+		},
+	}
+}
+
+func setReceiptEnv(t *testing.T) {
+	t.Setenv("RECEIPT_WIDTH", "32")
+	t.Setenv("STORE_NAME", "Toko Maju")
+	t.Setenv("STORE_ADDRESS", "Jl. Merdeka 1")
+	t.Setenv("STORE_PHONE", "")
+	t.Setenv("RECEIPT_FOOTER", "Thank you")
+}
+
+func TestText(t *testing.T) {
+	setReceiptEnv(t)
+
+	pending := testOrder()
+	pending.Status = models.OrderStatusPending
+	pending.PaymentStatus = models.PaymentStatusPartiallyPaid
+	pending.Payments = pending.Payments[:1]
+	pending.PointsEarned = 0
+
+	noPayments := testOrder()
+	noPayments.Payments = nil
+	noPayments.PaymentMethod = models.PaymentMethodEWallet
+
+	header := []string{
+		"           Toko Maju",
+		"         Jl. Merdeka 1",
+		"--------------------------------",
+		"Order #7        2026-10-19 14:30",
+		"Invoice INV/2026/10/000007",
+	}
+	items := []string{
+		"--------------------------------",
+		"Extra Large Premium Arabica",
+		"Coffee Beans",
+		"  2 x 50.00               100.00",
+		"  Discount                -10.00",
+		"FREE Mug",
+		"  1 x 15.00                 0.00",
+		"--------------------------------",
+		"Gross total               100.00",
+		"Item discounts            -10.00",
+		"Subtotal                   90.00",
+		"VAT 11.00% (incl.)          8.92",
+		"Points (5)                 -5.00",
+		"TOTAL                      85.00",
+		"--------------------------------",
+	}
+	footer := []string{
+		"--------------------------------",
+		"           Thank you",
+	}
+
+	tests := []struct {
+		name  string
+		order models.Order
+		want  [][]string
+	}{
+		{"completed split payment", testOrder(), [][]string{header, items, {
+			"Cash                       50.00",
+			"  Tendered                100.00",
+			"  Change                   50.00",
+			"Card APPROVAL-123456789012 35.00",
+			"Points earned                 85",
+		}, footer}},
+		{"pending partly paid", pending, [][]string{header, {
+			"            PENDING",
+		}, items, {
+			"Cash                       50.00",
+			"  Tendered                100.00",
+			"  Change                   50.00",
+			"Payment status    partially_paid",
+		}, footer}},
+		{"payment method only", noPayments, [][]string{header, items, {
+			"E-wallet                   85.00",
+			"Points earned                 85",
+		}, footer}},
+	}
+	for _, tt := range tests {
+		var want []string
+		for _, part := range tt.want {
+			want = append(want, part...)
+		}
+		got := string(FromOrder(tt.order).Text())
+		if expected := strings.Join(want, "\n") + "\n"; got != expected {
+			t.Errorf("%s: Text() =\n%s\nwant\n%s", tt.name, got, expected)
+		}
+	}
+}
+
+func TestTextStyles(t *testing.T) {
+	setReceiptEnv(t)
+
+	r := FromOrder(testOrder())
+	if r.Width != 32 {
+		t.Fatalf("Width = %d, want 32", r.Width)
+	}
+	bold := map[string]bool{}
+	for _, line := range r.Lines {
+		if line.Bold {
+			bold[strings.Fields(line.Text)[0]] = true
+		}
+	}
+	if len(bold) != 2 || !bold["Toko"] || !bold["TOTAL"] {
+		t.Errorf("bold lines = %v, want the store name and TOTAL", bold)
+	}
+}
+
+func TestESCPOS(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []Line
+		want  []byte
+	}{
+		{"empty", nil, []byte{
+			0x1b, 0x40,
+			0x1b, 0x64, 0x04,
+			0x1d, 0x56, 0x42, 0x00,
+		}},
+		{"styled lines", []Line{
+			{Text: "Shop", Bold: true, Align: AlignCenter},
+			{Text: "Tea"},
+			{Text: "TOTAL", Bold: true},
+		}, []byte{
+			0x1b, 0x40,
+			0x1b, 0x61, 0x01, 0x1b, 0x45, 0x01, 'S', 'h', 'o', 'p', '\n', 0x1b, 0x45, 0x00, 0x1b, 0x61, 0x00,
+			'T', 'e', 'a', '\n',
+			0x1b, 0x45, 0x01, 'T', 'O', 'T', 'A', 'L', '\n', 0x1b, 0x45, 0x00,
+			0x1b, 0x64, 0x04,
+			0x1d, 0x56, 0x42, 0x00,
+		}},
+	}
+	for _, tt := range tests {
+		r := Receipt{Width: 10, Lines: tt.lines}
+		if got := r.ESCPOS(); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: ESCPOS() = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	r := Receipt{Width: 10, Lines: []Line{
+		{Text: "Shop", Bold: true, Align: AlignCenter},
+		{Text: `(a\b)`},
+	}}
+	pdf := r.PDF()
+
+	for _, want := range []string{
+		"%PDF-1.4\n",
+		"/MediaBox [0 0 72.00 44.00]",
+		"/F2 8.0 Tf (   Shop) Tj T*\n",
+		`/F1 8.0 Tf (\(a\\b\)) Tj T*` + "\n",
+		"%%EOF\n",
+	} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF() does not contain %q", want)
+		}
+	}
+
+	// Every cross-reference entry must point at the start of its object
+	xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+	if len(xref) != 6 {
+		t.Fatalf("PDF() has %d xref entries, want 6", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, pdf[offset:offset+len(want)], want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package receipt
+
+import "strings"
+
+// Text renders the receipt as plain text.
+func (r Receipt) Text() []byte {
+	var b strings.Builder
+	for _, line := range r.Lines {
+		b.WriteString(r.padded(line))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
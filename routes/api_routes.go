@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"pos/handlers"
+
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(app *gin.Engine) {
+	app.GET("/.well-known/jwks.json", handlers.GetJWKS)
+
 	api := app.Group("/api")
 
 	SetupAuthRoutes(api)
@@ -12,6 +16,14 @@ func SetupRoutes(app *gin.Engine) {
 	SetupProfileRoutes(api)
 	SetupProductRoutes(api)
 	SetupCategoryRoutes(api)
+	SetupTaxClassRoutes(api)
+	SetupPriceListRoutes(api)
 	SetupPromotionRoutes(api)
+	SetupCustomerRoutes(api)
 	SetupOrderRoutes(api)
-}
\ No newline at end of file
+	SetupCartRoutes(api)
+	SetupPaymentRoutes(api)
+	SetupShiftRoutes(api)
+	SetupReportRoutes(api)
+	SetupDashboardRoutes(api)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"pos/handlers"
+	"pos/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,4 +12,5 @@ func SetupAuthRoutes(router *gin.RouterGroup) {
 	auth.POST("/register", handlers.Register)
 	auth.POST("/login", handlers.Login)
 	auth.POST("/refresh", handlers.RefreshToken)
+	auth.POST("/logout", middleware.Protected(), handlers.Logout)
 }
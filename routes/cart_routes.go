@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCartRoutes(router *gin.RouterGroup) {
+	router.GET("/cart", middleware.Protected(), handlers.GetCart)
+	router.DELETE("/cart", middleware.Protected(), handlers.ClearCart)
+	router.POST("/cart/items", middleware.Protected(), handlers.AddCartItem)
+	router.PATCH("/cart/items/:id", middleware.Protected(), handlers.UpdateCartItem)
+	router.DELETE("/cart/items/:id", middleware.Protected(), handlers.RemoveCartItem)
+	router.POST("/cart/checkout", middleware.Protected(), middleware.Idempotency(), handlers.CheckoutCart)
+}
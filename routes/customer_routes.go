@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCustomerRoutes(router *gin.RouterGroup) {
+	router.GET("/customers", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.GetCustomers)
+	router.GET("/customers/:id", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.GetCustomerByID)
+	router.GET("/customers/:id/orders", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.GetCustomerOrders)
+	router.POST("/customers", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.StoreCustomer)
+	router.PUT("/customers/:id", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.UpdateCustomerByID)
+	router.DELETE("/customers/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.DeleteCustomerByID)
+}
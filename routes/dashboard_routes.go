@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupDashboardRoutes(router *gin.RouterGroup) {
+	router.GET("/dashboard", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetDashboard)
+}
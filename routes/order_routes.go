@@ -8,7 +8,13 @@ import (
 )
 
 func SetupOrderRoutes(router *gin.RouterGroup) {
-	router.POST("/orders", middleware.Protected(), handlers.CreateOrder)
+	router.POST("/orders", middleware.Protected(), middleware.Idempotency(), handlers.CreateOrder)
+	router.POST("/orders/quote", middleware.Protected(), handlers.QuoteOrder)
 	router.GET("/orders", middleware.Protected(), handlers.GetOrders)
 	router.GET("/orders/:id", middleware.Protected(), handlers.GetOrderByID)
+	router.GET("/orders/:id/receipt", middleware.Protected(), handlers.GetOrderReceipt)
+	router.POST("/orders/:id/payments", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.AddOrderPayments)
+	router.PATCH("/orders/:id/items", middleware.Protected(), handlers.EditOrderItems)
+	router.POST("/orders/:id/cancel", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.CancelOrder)
+	router.POST("/orders/:id/refund", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.RefundOrder)
 }
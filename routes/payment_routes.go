@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPaymentRoutes(router *gin.RouterGroup) {
+	router.POST("/orders/:id/payment-intents", middleware.Protected(), handlers.CreatePaymentIntent)
+	router.POST("/payment-intents/:id/capture", middleware.Protected(), handlers.CapturePaymentIntent)
+	// Webhooks are authenticated by the provider's signature instead of a user token
+	router.POST("/payments/webhooks/:provider", handlers.HandlePaymentWebhook)
+}
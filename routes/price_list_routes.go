@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPriceListRoutes(router *gin.RouterGroup) {
+	router.GET("/price-lists", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetPriceLists)
+	router.GET("/price-lists/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetPriceListByID)
+	router.POST("/price-lists", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.StorePriceList)
+	router.PUT("/price-lists/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.UpdatePriceListByID)
+	router.DELETE("/price-lists/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.DeletePriceListByID)
+}
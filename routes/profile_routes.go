@@ -11,4 +11,7 @@ func SetupProfileRoutes(router *gin.RouterGroup) {
 	router.GET("/profile", middleware.Protected(), handlers.GetUserProfile)
 	router.PATCH("/profile", middleware.Protected(), handlers.GetUserProfile)
 	router.PATCH("/profile/password", middleware.Protected(), handlers.UpdateProfilePassword)
+	router.GET("/profile/loyalty", middleware.Protected(), handlers.GetProfileLoyalty)
+	router.GET("/profile/sessions", middleware.Protected(), handlers.GetProfileSessions)
+	router.DELETE("/profile/sessions/:id", middleware.Protected(), handlers.RevokeProfileSession)
 }
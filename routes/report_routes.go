@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupReportRoutes(router *gin.RouterGroup) {
+	router.GET("/reports/promotions/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetPromotionReport)
+	router.GET("/reports/reorder-suggestions", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetReorderSuggestions)
+	router.GET("/reports/inventory-abc", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetInventoryAnalysis)
+	router.GET("/reports/dead-stock", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetDeadStock)
+	router.GET("/reports/sales", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetSalesReport)
+	router.GET("/reports/tax", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetTaxSummary)
+}
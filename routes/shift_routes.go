@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupShiftRoutes(router *gin.RouterGroup) {
+	router.POST("/shifts", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.OpenShift)
+	router.GET("/shifts", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetShifts)
+	router.GET("/shifts/current", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.GetCurrentShift)
+	router.POST("/shifts/current/cash-events", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.AddShiftCashEvent)
+	router.POST("/shifts/current/close", middleware.Protected(), middleware.AuthorizeRole("admin", "cashier"), handlers.CloseShift)
+	router.GET("/shifts/:id/report", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetShiftReport)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"pos/handlers"
+	"pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupTaxClassRoutes(router *gin.RouterGroup) {
+	router.GET("/tax-classes", middleware.Protected(), handlers.GetTaxClasses)
+	router.GET("/tax-classes/:id", middleware.Protected(), handlers.GetTaxClassByID)
+	router.POST("/tax-classes", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.StoreTaxClass)
+	router.PUT("/tax-classes/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.UpdateTaxClassByID)
+	router.DELETE("/tax-classes/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.DeleteTaxClassByID)
+}
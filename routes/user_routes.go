@@ -12,4 +12,8 @@ func SetupUserRoutes(router *gin.RouterGroup) {
 	router.GET("/users/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetUserByID)
 	router.PATCH("/users/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.UpdateUserByID)
 	router.DELETE("/users/:id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.DeleteUserByID)
+	router.GET("/users/:id/loyalty", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetUserLoyalty)
+	router.GET("/users/:id/sessions", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.GetUserSessions)
+	router.DELETE("/users/:id/sessions", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.RevokeUserSessions)
+	router.DELETE("/users/:id/sessions/:session_id", middleware.Protected(), middleware.AuthorizeRole("admin"), handlers.RevokeUserSession)
 }
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache is a small in-memory cache whose entries expire after a fixed time.
+type TTLCache[V any] struct {
+	mu      sync.Mutex
+	entries map[string]ttlEntry[V]
+}
+
+type ttlEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func NewTTLCache[V any]() *TTLCache[V] {
+	return &TTLCache[V]{entries: map[string]ttlEntry[V]{}}
+}
+
+// Get returns the value stored under key if it has not expired yet.
+func (c *TTLCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores value under key for ttl. A ttl of zero or less does not store anything.
+func (c *TTLCache[V]) Set(key string, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = ttlEntry[V]{value: value, expiresAt: time.Now().Add(ttl)}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import "math"
+
+// MovingAverage returns the average of the last window values of series.
+func MovingAverage(series []float64, window int) float64 {
+	if window <= 0 || len(series) == 0 {
+		return 0
+	}
+	if window > len(series) {
+		window = len(series)
+	}
+	sum := 0.0
+	for _, value := range series[len(series)-window:] {
+		sum += value
+	}
+	return sum / float64(window)
+}
+
+// ExponentialSmoothing returns the simple exponential smoothing forecast of
+// the next value of series, oldest value first. alpha (0-1] weighs recent values;
+// higher values react faster to changes in demand.
+func ExponentialSmoothing(series []float64, alpha float64) float64 {
+	if len(series) == 0 {
+		return 0
+	}
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	level := series[0]
+	for _, value := range series[1:] {
+		level = alpha*value + (1-alpha)*level
+	}
+	return level
+}
+
+// ReorderPlan is the stock outlook of a product at a given daily demand.
+type ReorderPlan struct {
+	DaysOfStock       *float64 `json:"days_of_stock"`      // Days until the available stock runs out; nil when there is no demand
+	ReorderPoint      int      `json:"reorder_point"`      // Stock level that covers demand over the lead time plus safety days
+	SuggestedQuantity int      `json:"suggested_quantity"` // Quantity to order now to cover the lead time, cover and safety days
+	NeedsReorder      bool     `json:"needs_reorder"`
+}
+
+// PlanReorder works out when a product runs out and how much to order so that
+// stock arriving after leadTimeDays lasts another coverDays, keeping safetyDays
+// of demand in reserve.
+func PlanReorder(dailyDemand float64, available int, leadTimeDays, coverDays, safetyDays int) ReorderPlan {
+	var plan ReorderPlan
+	if dailyDemand <= 0 {
+		return plan
+	}
+
+	days := math.Round(float64(available)/dailyDemand*10) / 10
+	plan.DaysOfStock = &days
+	plan.ReorderPoint = int(math.Ceil(dailyDemand * float64(leadTimeDays+safetyDays)))
+	plan.NeedsReorder = available <= plan.ReorderPoint
+
+	target := int(math.Ceil(dailyDemand * float64(leadTimeDays+coverDays+safetyDays)))
+	if plan.NeedsReorder && target > available {
+		plan.SuggestedQuantity = target - available
+	}
+	return plan
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"pos/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrMissingSigningKey = errors.New("missing JWT signing key")
+)
+
+// AccessTokenClaims are the claims of an access token: the standard sub, iss,
+// aud, iat, exp and jti claims plus the user's token version.
+type AccessTokenClaims struct {
+	jwt.RegisteredClaims
+	TokenVersion int `json:"token_version"` // Must match the user's token version for the token to be accepted
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet is the document served on the JWKS endpoint.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// rsaKeyCache keeps the parsed private key of JWT_PRIVATE_KEY_PATH.
+var rsaKeyCache struct {
+	sync.Mutex
+	path string
+	key  *rsa.PrivateKey
+}
+
+// JWTAlgorithm returns the configured signing algorithm: HS256 (default) or RS256.
+func JWTAlgorithm() string {
+	if config.LoadConfig("JWT_ALGORITHM") == "RS256" {
+		return "RS256"
+	}
+	return "HS256"
+}
+
+// JWTIssuer returns the iss claim of issued tokens.
+func JWTIssuer() string {
+	if issuer := config.LoadConfig("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "pos-api"
+}
+
+// JWTAudience returns the aud claim of issued tokens.
+func JWTAudience() string {
+	if audience := config.LoadConfig("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return "pos-api"
+}
+
+// AccessTokenTTL returns how long access tokens are valid.
+func AccessTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(config.LoadConfig("ACCESS_TOKEN_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// rsaPrivateKey loads the RS256 signing key from the PEM file at JWT_PRIVATE_KEY_PATH.
+func rsaPrivateKey() (*rsa.PrivateKey, error) {
+	path := config.LoadConfig("JWT_PRIVATE_KEY_PATH")
+	if path == "" {
+		return nil, ErrMissingSigningKey
+	}
+
+	rsaKeyCache.Lock()
+	defer rsaKeyCache.Unlock()
+	if rsaKeyCache.key != nil && rsaKeyCache.path == path {
+		return rsaKeyCache.key, nil
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMissingSigningKey, err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+	rsaKeyCache.path = path
+	rsaKeyCache.key = key
+	return key, nil
+}
+
+// hmacSecret returns the HS256 secret from JWT_SECRET.
+func hmacSecret() ([]byte, error) {
+	secret := config.LoadConfig("JWT_SECRET")
+	if secret == "" {
+		return nil, ErrMissingSigningKey
+	}
+	return []byte(secret), nil
+}
+
+// publicJWK returns the JSON Web Key of an RSA public key. Its key ID is the
+// RFC 7638 thumbprint unless JWT_KEY_ID is set.
+func publicJWK(key *rsa.PublicKey) JWK {
+	jwk := JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+
+	jwk.Kid = config.LoadConfig("JWT_KEY_ID")
+	if jwk.Kid == "" {
+		// Members in lexicographic order, as the thumbprint requires
+		thumbprint, _ := json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+		sum := sha256.Sum256(thumbprint)
+		jwk.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return jwk
+}
+
+// SignAccessToken signs claims with the configured algorithm and key.
+func SignAccessToken(claims jwt.Claims) (string, error) {
+	if JWTAlgorithm() == "RS256" {
+		key, err := rsaPrivateKey()
+		if err != nil {
+			return "", err
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = publicJWK(&key.PublicKey).Kid
+		return token.SignedString(key)
+	}
+
+	secret, err := hmacSecret()
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseAccessToken verifies tokenString into claims. Only the configured
+// algorithm is accepted, and the token must carry the expected issuer and
+// audience, a subject, an ID and issue and expiry times.
+func ParseAccessToken(tokenString string, claims *AccessTokenClaims) (*jwt.Token, error) {
+	algorithm := JWTAlgorithm()
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		if algorithm == "RS256" {
+			key, err := rsaPrivateKey()
+			if err != nil {
+				return nil, err
+			}
+			return &key.PublicKey, nil
+		}
+		return hmacSecret()
+	}, jwt.WithValidMethods([]string{algorithm}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	switch {
+	case !token.Valid:
+		return nil, ErrInvalidToken
+	case !claims.VerifyIssuer(JWTIssuer(), true):
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	case !claims.VerifyAudience(JWTAudience(), true):
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	case claims.Subject == "" || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: missing claims", ErrInvalidToken)
+	}
+	return token, nil
+}
+
+// PublicJWKS returns the keys other services can verify access tokens with.
+// It is empty when tokens are signed with the shared HS256 secret.
+func PublicJWKS() (JWKSet, error) {
+	set := JWKSet{Keys: []JWK{}}
+	if JWTAlgorithm() != "RS256" {
+		return set, nil
+	}
+
+	key, err := rsaPrivateKey()
+	if err != nil {
+		return set, err
+	}
+	set.Keys = append(set.Keys, publicJWK(&key.PublicKey))
+	return set, nil
+}
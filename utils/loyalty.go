@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"pos/config"
+	"pos/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+// LoyaltyEarnRate returns the points earned per currency unit of an order's total amount.
+func LoyaltyEarnRate() float64 {
+	return loyaltyConfigFloat("LOYALTY_EARN_RATE", 0.01)
+}
+
+// LoyaltyPointValue returns the currency value of a single point when redeemed.
+func LoyaltyPointValue() models.Money {
+	value, err := models.ParseMoney(config.LoadConfig("LOYALTY_POINT_VALUE"))
+	if err != nil || value < 0 {
+		return models.MoneyFromUnits(1)
+	}
+	return value
+}
+
+// loyaltyExpiry returns when points added now expire, or nil if they never do.
+func loyaltyExpiry() *time.Time {
+	days := loyaltyConfigFloat("LOYALTY_POINTS_EXPIRY_DAYS", 365)
+	if days <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().AddDate(0, 0, int(days))
+	return &expiresAt
+}
+
+func loyaltyConfigFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(config.LoadConfig(key), 64)
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// ExpireLoyaltyPoints writes off the unspent part of the user's entries that passed their expiry date.
+func ExpireLoyaltyPoints(tx *gorm.DB, userID uint) error {
+	var expired []models.LoyaltyTransaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining_points > 0 AND expires_at <= ?", userID, time.Now()).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range expired {
+		if err := tx.Create(&models.LoyaltyTransaction{
+			UserID:  userID,
+			Type:    models.LoyaltyTransactionExpire,
+			Points:  -entry.RemainingPoints,
+			OrderID: entry.OrderID,
+			Notes:   fmt.Sprintf("Expired points from entry %d", entry.ID),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entry).Update("remaining_points", 0).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoyaltyBalance expires old points and returns the user's current points balance.
+func LoyaltyBalance(tx *gorm.DB, userID uint) (int, error) {
+	if err := ExpireLoyaltyPoints(tx, userID); err != nil {
+		return 0, err
+	}
+
+	var balance int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Select("COALESCE(SUM(points), 0)").
+		Where("user_id = ?", userID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// PointsDiscount returns how many of the requested points are needed to cover
+// amountDue and the discount they are worth.
+func PointsDiscount(requestedPoints int, amountDue models.Money) (int, models.Money) {
+	value := LoyaltyPointValue()
+	if requestedPoints <= 0 || value <= 0 || amountDue <= 0 {
+		return 0, 0
+	}
+
+	points := requestedPoints
+	if needed := int((amountDue + value - 1) / value); needed < points {
+		points = needed
+	}
+	return points, value.MulInt(points).Min(amountDue)
+}
+
+// RedeemLoyaltyPoints spends points for an order, consuming the entries that expire first.
+func RedeemLoyaltyPoints(tx *gorm.DB, userID uint, orderID uint, points int) error {
+	if points <= 0 {
+		return nil
+	}
+
+	if err := ExpireLoyaltyPoints(tx, userID); err != nil {
+		return err
+	}
+
+	// The balance is worked out from the locked entries, so concurrent orders
+	// cannot both spend the same points
+	var entries []models.LoyaltyTransaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining_points > 0 AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Order("expires_at ASC NULLS LAST, id ASC").
+		Find(&entries).Error; err != nil {
+		return err
+	}
+	available := 0
+	for _, entry := range entries {
+		available += entry.RemainingPoints
+	}
+	if available < points {
+		return fmt.Errorf("%w: %d available", ErrInsufficientPoints, available)
+	}
+
+	left := points
+	for _, entry := range entries {
+		if left == 0 {
+			break
+		}
+		used := min(entry.RemainingPoints, left)
+		if err := tx.Model(&entry).Update("remaining_points", entry.RemainingPoints-used).Error; err != nil {
+			return err
+		}
+		left -= used
+	}
+	if left != 0 {
+		return fmt.Errorf("%w: %d points short", ErrInsufficientPoints, left)
+	}
+
+	return tx.Create(&models.LoyaltyTransaction{
+		UserID:  userID,
+		OrderID: &orderID,
+		Type:    models.LoyaltyTransactionRedeem,
+		Points:  -points,
+		Notes:   fmt.Sprintf("Redeemed on order %d", orderID),
+	}).Error
+}
+
+// AwardLoyaltyPoints credits the points earned on an order's total amount and returns them.
+func AwardLoyaltyPoints(tx *gorm.DB, userID uint, orderID uint, totalAmount models.Money) (int, error) {
+	points := int(math.Floor(totalAmount.Float64() * LoyaltyEarnRate()))
+	if points <= 0 {
+		return 0, nil
+	}
+
+	err := tx.Create(&models.LoyaltyTransaction{
+		UserID:          userID,
+		OrderID:         &orderID,
+		Type:            models.LoyaltyTransactionEarn,
+		Points:          points,
+		RemainingPoints: points,
+		ExpiresAt:       loyaltyExpiry(),
+		Notes:           fmt.Sprintf("Earned on order %d", orderID),
+	}).Error
+	return points, err
+}
+
+// ReverseOrderLoyalty takes back the points earned on a cancelled or refunded
+// order and returns the points redeemed on it. Only the earned points that are
+// still unspent and unexpired are taken back; expired points were already
+// written off by their expire entry.
+func ReverseOrderLoyalty(tx *gorm.DB, order models.Order) error {
+	// Orders without an account never earn or redeem points
+	if order.UserID == nil {
+		return nil
+	}
+	userID := *order.UserID
+
+	if order.PointsEarned > 0 {
+		if err := ExpireLoyaltyPoints(tx, userID); err != nil {
+			return err
+		}
+
+		var earned []models.LoyaltyTransaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND type = ? AND remaining_points > 0", order.ID, models.LoyaltyTransactionEarn).
+			Find(&earned).Error; err != nil {
+			return err
+		}
+		remaining := 0
+		for _, entry := range earned {
+			remaining += entry.RemainingPoints
+			if err := tx.Model(&entry).Update("remaining_points", 0).Error; err != nil {
+				return err
+			}
+		}
+
+		if remaining > 0 {
+			if err := tx.Create(&models.LoyaltyTransaction{
+				UserID:  userID,
+				OrderID: &order.ID,
+				Type:    models.LoyaltyTransactionReverse,
+				Points:  -remaining,
+				Notes:   fmt.Sprintf("Earned points reversed for %s order %d", order.Status, order.ID),
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if order.PointsRedeemed > 0 {
+		if err := tx.Create(&models.LoyaltyTransaction{
+			UserID:          userID,
+			OrderID:         &order.ID,
+			Type:            models.LoyaltyTransactionReverse,
+			Points:          order.PointsRedeemed,
+			RemainingPoints: order.PointsRedeemed,
+			ExpiresAt:       loyaltyExpiry(),
+			Notes:           fmt.Sprintf("Redeemed points returned for %s order %d", order.Status, order.ID),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
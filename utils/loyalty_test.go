@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+
+	"pos/models"
+)
+
+func TestPointsDiscount(t *testing.T) {
+	tests := []struct {
+		name         string
+		pointValue   string
+		requested    int
+		amountDue    models.Money
+		wantPoints   int
+		wantDiscount models.Money
+	}{
+		{"covers part of the amount", "", 10, 5000, 10, 1000},
+		{"covers all of the amount", "", 10, 1000, 10, 1000},
+		{"only the points needed are spent", "", 100, 2550, 26, 2550},
+		{"no points requested", "", 0, 5000, 0, 0},
+		{"negative points", "", -5, 5000, 0, 0},
+		{"nothing due", "", 10, 0, 0, 0},
+		{"custom point value", "0.50", 10, 300, 6, 300},
+		{"fractional point value", "0.30", 4, 5000, 4, 120},
+		{"points worth nothing", "0", 10, 5000, 0, 0},
+		{"invalid point value falls back to 1.00", "abc", 10, 5000, 10, 1000},
+	}
+	for _, tt := range tests {
+		t.Setenv("LOYALTY_POINT_VALUE", tt.pointValue)
+		points, discount := PointsDiscount(tt.requested, tt.amountDue)
+		if points != tt.wantPoints || discount != tt.wantDiscount {
+			t.Errorf("%s: PointsDiscount(%d, %s) = %d, %s, want %d, %s", tt.name, tt.requested, tt.amountDue, points, discount, tt.wantPoints, tt.wantDiscount)
+		}
+	}
+}
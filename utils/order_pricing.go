@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"pos/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductNotFound          = errors.New("product not found")
+	ErrPromotionProductNotFound = errors.New("get product for promotion not found")
+)
+
+// OrderQuoteItem is a single requested line of an order or quote.
+type OrderQuoteItem struct {
+	ProductID uint
+	Quantity  int
+}
+
+// OrderQuoteLine is a priced order line. Free items granted by a promotion on
+// this line are listed in FreeItems.
+type OrderQuoteLine struct {
+	Product           models.Product           `json:"-"`
+	ProductID         uint                     `json:"product_id"`
+	ProductName       string                   `json:"product_name"`
+	Quantity          int                      `json:"quantity"`
+	Price             models.Money             `json:"price"`            // Original price per unit
+	GrossTotal        models.Money             `json:"gross_total"`      // Price * Quantity
+	ItemDiscount      models.Money             `json:"item_discount"`    // Total discount for all units of this line
+	DiscountedPrice   models.Money             `json:"discounted_price"` // Total price for all units of this line after discount
+	IsFreeItem        bool                     `json:"is_free_item"`
+	CartDiscountShare models.Money             `json:"cart_discount_share"` // Part of the cart discount allocated to this line
+	TaxClassID        *uint                    `json:"tax_class_id,omitempty"`
+	TaxRate           models.Money             `json:"tax_rate"`
+	TaxInclusive      bool                     `json:"tax_inclusive"`
+	TaxableAmount     models.Money             `json:"taxable_amount"` // Tax base after item and cart discounts, excluding the tax itself
+	TaxAmount         models.Money             `json:"tax_amount"`
+	AppliedPromotion  *models.ProductPromotion `json:"applied_promotion,omitempty"`
+	FreeItems         []OrderQuoteLine         `json:"free_items,omitempty"`
+}
+
+// OrderQuote is the full price breakdown of an order.
+type OrderQuote struct {
+	Lines                []OrderQuoteLine      `json:"lines"`
+	GrossTotal           models.Money          `json:"gross_total"`
+	ItemDiscountTotal    models.Money          `json:"item_discount_total"`
+	SubTotal             models.Money          `json:"sub_total"`
+	CartDiscount         models.Money          `json:"cart_discount"`
+	AppliedCartPromotion *models.CartPromotion `json:"applied_cart_promotion,omitempty"`
+	PriceListID          *uint                 `json:"price_list_id,omitempty"` // Price list the items were priced from
+	TaxTotal             models.Money          `json:"tax_total"`               // Inclusive and exclusive tax; only exclusive tax is added to TotalAmount
+	Taxes                []TaxBreakdown        `json:"taxes"`
+	TotalAmount          models.Money          `json:"total_amount"`
+}
+
+// QuoteOrder prices the given items from priceList (nil for list prices) with the
+// active product and cart promotions and calculates tax on what is left after
+// item and cart discounts.
+// It only reads from db, so it is shared by checkout and by side-effect free previews.
+func QuoteOrder(db *gorm.DB, items []OrderQuoteItem, priceList *models.PriceList) (OrderQuote, error) {
+	var quote OrderQuote
+	if priceList != nil {
+		quote.PriceListID = &priceList.ID
+	}
+
+	for _, item := range items {
+		var product models.Product
+		if err := db.Preload("Promotions").Preload("TaxClass").Preload("Category.TaxClass").First(&product, item.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return quote, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+			}
+			return quote, err
+		}
+
+		// Calculate total price for the item, considering quantity and promotions
+		totalItemPrice, activePromotion := CalculateTotalPrice(product, item.Quantity, priceList)
+		unitPrice := PriceListUnitPrice(priceList, product, item.Quantity)
+		originalItemTotal := unitPrice.MulInt(item.Quantity)
+
+		line := OrderQuoteLine{
+			Product:          product,
+			ProductID:        product.ID,
+			ProductName:      product.Name,
+			Quantity:         item.Quantity,
+			Price:            unitPrice,
+			GrossTotal:       originalItemTotal,
+			ItemDiscount:     originalItemTotal - totalItemPrice,
+			DiscountedPrice:  totalItemPrice,
+			AppliedPromotion: activePromotion,
+		}
+
+		// Handle "buy X get Y" promotion
+		if activePromotion != nil && activePromotion.PromotionType == "buy_x_get_y" {
+			if activePromotion.BuyProductID != nil && activePromotion.GetProductID != nil && *activePromotion.BuyProductID == product.ID {
+				// Assuming for simplicity, 1 quantity of Y for 1 quantity of X
+				var getProduct models.Product
+				if err := db.First(&getProduct, *activePromotion.GetProductID).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return quote, fmt.Errorf("%w: %d", ErrPromotionProductNotFound, *activePromotion.GetProductID)
+					}
+					return quote, err
+				}
+				// Add the 'get Y' product as a free item (DiscountedPrice is 0)
+				freeTotal := getProduct.Price.MulInt(item.Quantity)
+				line.FreeItems = append(line.FreeItems, OrderQuoteLine{
+					Product:         getProduct,
+					ProductID:       getProduct.ID,
+					ProductName:     getProduct.Name,
+					Quantity:        item.Quantity,    // Same quantity as the 'buy X' product
+					Price:           getProduct.Price, // Original price of the free item
+					GrossTotal:      freeTotal,
+					DiscountedPrice: 0,         // Free item
+					ItemDiscount:    freeTotal, // Discount is the full price of all free units
+					IsFreeItem:      true,
+				})
+			}
+		}
+
+		quote.Lines = append(quote.Lines, line)
+		quote.GrossTotal += line.GrossTotal
+		quote.ItemDiscountTotal += line.ItemDiscount
+	}
+
+	quote.SubTotal = quote.GrossTotal - quote.ItemDiscountTotal
+
+	// Calculate cart-level discount
+	quote.CartDiscount, quote.AppliedCartPromotion = CalculateCartDiscount(quote.SubTotal)
+
+	quote.TotalAmount = quote.SubTotal - quote.CartDiscount
+
+	// Calculate tax per line after item and cart discounts
+	allocateCartDiscount(quote.Lines, quote.CartDiscount)
+	for i := range quote.Lines {
+		line := &quote.Lines[i]
+		taxClass := ProductTaxClass(line.Product)
+		if taxClass == nil {
+			line.TaxableAmount = line.DiscountedPrice - line.CartDiscountShare
+			continue
+		}
+
+		line.TaxClassID = &taxClass.ID
+		line.TaxRate = taxClass.Rate
+		line.TaxInclusive = taxClass.Inclusive
+		line.TaxableAmount, line.TaxAmount = CalculateTax(line.DiscountedPrice-line.CartDiscountShare, taxClass)
+		if line.TaxAmount == 0 {
+			continue
+		}
+
+		quote.TaxTotal += line.TaxAmount
+		if !taxClass.Inclusive {
+			quote.TotalAmount += line.TaxAmount
+		}
+		quote.addTax(*taxClass, line.TaxableAmount, line.TaxAmount)
+	}
+
+	return quote, nil
+}
+
+// addTax adds a line's tax to the order breakdown of its tax class and rate.
+func (quote *OrderQuote) addTax(taxClass models.TaxClass, taxable models.Money, tax models.Money) {
+	for i := range quote.Taxes {
+		breakdown := &quote.Taxes[i]
+		if *breakdown.TaxClassID == taxClass.ID && breakdown.Rate == taxClass.Rate && breakdown.Inclusive == taxClass.Inclusive {
+			breakdown.TaxableAmount += taxable
+			breakdown.TaxAmount += tax
+			return
+		}
+	}
+	quote.Taxes = append(quote.Taxes, TaxBreakdown{
+		TaxClassID:    &taxClass.ID,
+		Name:          taxClass.Name,
+		Rate:          taxClass.Rate,
+		Inclusive:     taxClass.Inclusive,
+		TaxableAmount: taxable,
+		TaxAmount:     tax,
+	})
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+
+	"pos/models"
+
+	"gorm.io/gorm"
+)
+
+// ActivePriceList returns the price list that applies to a customer: their
+// own list when it is active, otherwise the active default list. It returns
+// nil when products sell at their list price. customerID may be nil.
+func ActivePriceList(db *gorm.DB, customerID *uint) (*models.PriceList, error) {
+	if customerID != nil {
+		var customer models.Customer
+		if err := db.First(&customer, *customerID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if customer.PriceListID != nil {
+			priceList, err := LoadPriceList(db, *customer.PriceListID)
+			if err != nil || priceList != nil {
+				return priceList, err
+			}
+		}
+	}
+
+	var priceList models.PriceList
+	err := db.Preload("Items").Where("is_default = ? AND active = ?", true, true).First(&priceList).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+// LoadPriceList returns an active price list with its items, or nil when it
+// does not exist or is inactive.
+func LoadPriceList(db *gorm.DB, id uint) (*models.PriceList, error) {
+	var priceList models.PriceList
+	err := db.Preload("Items").Where("active = ?", true).First(&priceList, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+// PriceListUnitPrice returns the unit price of a product bought in quantity
+// under priceList. The item with the highest minimum quantity not above
+// quantity wins; without one the list's own discount applies. A nil list
+// leaves the product at its list price.
+func PriceListUnitPrice(priceList *models.PriceList, product models.Product, quantity int) models.Money {
+	if priceList == nil {
+		return product.Price
+	}
+
+	var best *models.PriceListItem
+	for i := range priceList.Items {
+		item := &priceList.Items[i]
+		if item.ProductID != product.ID || item.MinQuantity > quantity {
+			continue
+		}
+		if best == nil || item.MinQuantity > best.MinQuantity {
+			best = item
+		}
+	}
+
+	discountPercent := priceList.DiscountPercent
+	if best != nil {
+		if best.Price != nil {
+			return *best.Price
+		}
+		if best.DiscountPercent != nil {
+			discountPercent = *best.DiscountPercent
+		}
+	}
+	return product.Price - product.Price.Percent(discountPercent)
+}
@@ -7,21 +7,27 @@ import (
 	"pos/models"
 )
 
-// CalculateTotalPrice calculates the total price for a given quantity of a product, applying the best active promotion.
-func CalculateTotalPrice(product models.Product, quantity int) (float64, *models.ProductPromotion) {
+// CalculateTotalPrice calculates the total price for a given quantity of a product, applying its active promotion.
+// Only one promotion applies: when several are active, stacked or not, the one that started first wins.
+// Prices start from the product's unit price on priceList, or its list price when priceList is nil.
+func CalculateTotalPrice(product models.Product, quantity int, priceList *models.PriceList) (models.Money, *models.ProductPromotion) {
 	now := time.Now()
 	var activePromotion *models.ProductPromotion
 
-	// Find the first active promotion for the product
+	// Find the earliest active promotion for the product, by ID when they start together
 	for i := range product.Promotions {
 		p := &product.Promotions[i]
-		if now.After(p.StartDate) && now.Before(p.EndDate) {
+		if !now.After(p.StartDate) || !now.Before(p.EndDate) {
+			continue
+		}
+		if activePromotion == nil || p.StartDate.Before(activePromotion.StartDate) ||
+			(p.StartDate.Equal(activePromotion.StartDate) && p.ID < activePromotion.ID) {
 			activePromotion = p
-			break
 		}
 	}
 
-	totalPrice := product.Price * float64(quantity)
+	unitPrice := PriceListUnitPrice(priceList, product, quantity)
+	totalPrice := unitPrice.MulInt(quantity)
 
 	if activePromotion != nil {
 		switch activePromotion.PromotionType {
@@ -29,14 +35,14 @@ func CalculateTotalPrice(product models.Product, quantity int) (float64, *models
 			if activePromotion.RequiredQuantity != nil && activePromotion.PromoPrice != nil && *activePromotion.RequiredQuantity > 0 {
 				numBundles := quantity / *activePromotion.RequiredQuantity
 				remainingItems := quantity % *activePromotion.RequiredQuantity
-				totalPrice = float64(numBundles)*(*activePromotion.PromoPrice) + float64(remainingItems)*product.Price
+				totalPrice = activePromotion.PromoPrice.MulInt(numBundles) + unitPrice.MulInt(remainingItems)
 			}
 		case "percentage_discount":
-			discountedPrice := product.Price * (1 - activePromotion.DiscountValue/100)
-			totalPrice = discountedPrice * float64(quantity)
+			// Round once on the line total rather than per unit
+			totalPrice -= totalPrice.Percent(activePromotion.DiscountValue)
 		case "fixed_discount":
-			discountedPrice := product.Price - activePromotion.DiscountValue
-			totalPrice = discountedPrice * float64(quantity)
+			discountedPrice := unitPrice - activePromotion.DiscountValue
+			totalPrice = discountedPrice.MulInt(quantity)
 		}
 		if totalPrice < 0 {
 			totalPrice = 0
@@ -47,7 +53,8 @@ func CalculateTotalPrice(product models.Product, quantity int) (float64, *models
 }
 
 // CalculateCartDiscount calculates a cart-level discount based on total purchase amount.
-func CalculateCartDiscount(subTotal float64) float64 {
+// It also returns the cart promotion that produced the discount, or nil if none applies.
+func CalculateCartDiscount(subTotal models.Money) (models.Money, *models.CartPromotion) {
 	var activeCartPromotion models.CartPromotion
 	now := time.Now()
 
@@ -60,10 +67,10 @@ func CalculateCartDiscount(subTotal float64) float64 {
 	if activeCartPromotion.ID != 0 {
 		switch activeCartPromotion.PromotionType {
 		case "percentage_discount":
-			return subTotal * (activeCartPromotion.DiscountValue / 100)
+			return subTotal.Percent(activeCartPromotion.DiscountValue).Min(subTotal), &activeCartPromotion
 		case "fixed_discount":
-			return activeCartPromotion.DiscountValue
+			return activeCartPromotion.DiscountValue.Min(subTotal), &activeCartPromotion
 		}
 	}
-	return 0.0
+	return 0, nil
 }
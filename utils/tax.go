@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"pos/models"
+)
+
+// TaxBreakdown is the tax charged for one tax class and rate across an order.
+type TaxBreakdown struct {
+	TaxClassID    *uint        `json:"tax_class_id"`
+	Name          string       `json:"name"`
+	Rate          models.Money `json:"rate"`
+	Inclusive     bool         `json:"inclusive"`
+	TaxableAmount models.Money `json:"taxable_amount"`
+	TaxAmount     models.Money `json:"tax_amount"`
+}
+
+// ProductTaxClass returns the tax class of the product, falling back to the
+// tax class of its category, or nil when neither has one. The product must be
+// loaded with its TaxClass and Category.TaxClass.
+func ProductTaxClass(product models.Product) *models.TaxClass {
+	if product.TaxClassID != nil && product.TaxClass.ID != 0 {
+		return &product.TaxClass
+	}
+	if product.Category.TaxClassID != nil && product.Category.TaxClass.ID != 0 {
+		return &product.Category.TaxClass
+	}
+	return nil
+}
+
+// CalculateTax splits a net line amount into the taxable base and the tax.
+// For inclusive classes the tax is carved out of the amount; for exclusive
+// classes it is charged on top of it.
+func CalculateTax(amount models.Money, taxClass *models.TaxClass) (base models.Money, tax models.Money) {
+	if taxClass == nil || taxClass.Rate <= 0 || amount <= 0 {
+		return amount, 0
+	}
+	if taxClass.Inclusive {
+		// tax = amount * rate / (100 + rate), with the rate held in cents of a percent
+		tax = amount.MulRat(int64(taxClass.Rate), int64(models.MoneyFromUnits(100)+taxClass.Rate), models.ConfiguredRounding())
+		return amount - tax, tax
+	}
+	return amount, amount.Percent(taxClass.Rate)
+}
+
+// allocateCartDiscount spreads the cart discount over the paid lines in
+// proportion to their discounted price. The last line takes the rounding
+// remainder so the shares always add up to the discount.
+func allocateCartDiscount(lines []OrderQuoteLine, discount models.Money) {
+	var total models.Money
+	last := -1
+	for i, line := range lines {
+		if line.DiscountedPrice > 0 {
+			total += line.DiscountedPrice
+			last = i
+		}
+	}
+	if discount <= 0 || total <= 0 {
+		return
+	}
+
+	remaining := discount
+	for i := range lines {
+		if lines[i].DiscountedPrice <= 0 {
+			continue
+		}
+		share := discount.MulRat(int64(lines[i].DiscountedPrice), int64(total), models.ConfiguredRounding())
+		if i == last {
+			share = remaining
+		}
+		share = share.Min(lines[i].DiscountedPrice).Min(remaining)
+		lines[i].CartDiscountShare = share
+		remaining -= share
+	}
+}
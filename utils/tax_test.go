@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"pos/models"
+)
+
+func TestCalculateTax(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   models.Money
+		taxClass *models.TaxClass
+		wantBase models.Money
+		wantTax  models.Money
+	}{
+		{"no tax class", 10000, nil, 10000, 0},
+		{"zero rate", 10000, &models.TaxClass{Rate: 0}, 10000, 0},
+		{"nothing to tax", 0, &models.TaxClass{Rate: 1100}, 0, 0},
+		{"negative amount", -500, &models.TaxClass{Rate: 1100}, -500, 0},
+		{"exclusive", 10000, &models.TaxClass{Rate: 1100}, 10000, 1100},
+		{"exclusive rounds half up", 5, &models.TaxClass{Rate: 1000}, 5, 1},
+		{"exclusive fractional rate", 10000, &models.TaxClass{Rate: 1250}, 10000, 1250},
+		{"inclusive", 11100, &models.TaxClass{Rate: 1100, Inclusive: true}, 10000, 1100},
+		{"inclusive rounds", 1000, &models.TaxClass{Rate: 1000, Inclusive: true}, 909, 91},
+		{"inclusive full rate", 1000, &models.TaxClass{Rate: 10000, Inclusive: true}, 500, 500},
+	}
+	for _, tt := range tests {
+		base, tax := CalculateTax(tt.amount, tt.taxClass)
+		if base != tt.wantBase || tax != tt.wantTax {
+			t.Errorf("%s: CalculateTax(%s) = %s, %s, want %s, %s", tt.name, tt.amount, base, tax, tt.wantBase, tt.wantTax)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package validators
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
+	"pos/models"
+
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
@@ -13,6 +17,18 @@ var DB *gorm.DB
 func RegisterCustomValidators(v *validator.Validate, db *gorm.DB) {
 	DB = db
 
+	// report fields by their JSON names so errors match the request body
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return fld.Name
+		}
+		return name
+	})
+
 	v.RegisterValidation("exists", existsValidator)
 }
 
@@ -31,3 +47,55 @@ func existsValidator(fl validator.FieldLevel) bool {
 	err := DB.Table(tableName).Where(fmt.Sprintf("%s = ?", columnName), value).Count(&count).Error
 	return err == nil && count > 0
 }
+
+// FieldErrors converts a binding error into a list of per-field errors.
+// Errors that are not validation errors (e.g. malformed JSON) are reported
+// against the request body as a whole.
+func FieldErrors(err error) []models.FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []models.FieldError{{Field: "body", Message: err.Error()}}
+	}
+
+	fieldErrors := make([]models.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fieldErrors = append(fieldErrors, models.FieldError{
+			Field:   fieldPath(fe),
+			Message: fieldMessage(fe),
+		})
+	}
+	return fieldErrors
+}
+
+// fieldPath strips the top-level struct name from the error namespace,
+// e.g. "CreateOrderInput.items[0].product_id" becomes "items[0].product_id".
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "exists":
+		return "does not exist"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "eqfield":
+		return "must match " + fe.Param()
+	default:
+		return fmt.Sprintf("failed on the '%s' validation", fe.Tag())
+	}
+}